@@ -0,0 +1,148 @@
+package cassete
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"go-vcr/internal/binrec"
+	"go-vcr/track"
+)
+
+// binaryMagic starts the binary cassete files.
+const binaryMagic = "VCRB"
+
+// binaryCodec encodes the cassete to the length-prefixed binary records.
+// The first record holds the format version and the cassete ID,
+// each of the next ones holds a track with its key in the cassete.
+// The keys, names and function types are written through the string table,
+// so the repeated ones take a few bytes.
+type binaryCodec struct{}
+
+func (binaryCodec) Encode(w io.Writer, c *Cassete) error {
+	d, err := c.Dump()
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, binaryMagic)
+	if err != nil {
+		return err
+	}
+
+	e := binrec.NewEncoder(w)
+	e.Uvarint(FormatVersion)
+	e.Uvarint(d.ID)
+	err = e.Flush()
+	if err != nil {
+		return err
+	}
+
+	for i := range d.Tracks {
+		err := encodeBinaryTrack(e, &d.Tracks[i])
+		if err != nil {
+			return err
+		}
+
+		err = e.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeBinaryTrack(e *binrec.Encoder, tr *track.Dump) error {
+	e.Ref(string(tr.Key))
+	e.Ref(tr.Name)
+	e.Varint(int64(tr.Seq))
+	e.Ref(tr.Func)
+	e.Uvarint(uint64(tr.KeyVersion))
+	e.Bool(tr.IsRecorded)
+	e.Varint(int64(tr.Duration))
+
+	e.Uvarint(uint64(len(tr.Args)))
+	for _, arg := range tr.Args {
+		err := e.Value(arg)
+		if err != nil {
+			return err
+		}
+	}
+
+	e.Uvarint(uint64(len(tr.Results)))
+	for _, result := range tr.Results {
+		err := e.Value(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.Value(tr.Panic)
+}
+
+func (binaryCodec) Decode(r io.Reader, c *Cassete) error {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(binaryMagic))
+	_, err := io.ReadFull(br, magic)
+	if err != nil {
+		return err
+	}
+	if string(magic) != binaryMagic {
+		return fmt.Errorf("%w: no binary cassete header", binrec.ErrCorrupted)
+	}
+
+	d := binrec.NewDecoder(br)
+	if !d.Next() {
+		if d.Err() != nil {
+			return d.Err()
+		}
+		return io.ErrUnexpectedEOF
+	}
+	version := d.Uvarint()
+	id := d.Uvarint()
+	if d.Err() != nil {
+		return d.Err()
+	}
+	if version != FormatVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedVersion, version)
+	}
+
+	cas := Dump{ID: id}
+	for d.Next() {
+		cas.Tracks = append(cas.Tracks, decodeBinaryTrack(d))
+	}
+	if d.Err() != nil {
+		return d.Err()
+	}
+
+	return c.Restore(cas)
+}
+
+func decodeBinaryTrack(d *binrec.Decoder) track.Dump {
+	tr := track.Dump{
+		Key:        track.Key(d.Ref()),
+		Name:       d.Ref(),
+		Seq:        int(d.Varint()),
+		Func:       d.Ref(),
+		KeyVersion: int(d.Uvarint()),
+		IsRecorded: d.Bool(),
+		Duration:   time.Duration(d.Varint()),
+	}
+
+	tr.Args = make([]interface{}, d.Length())
+	for i := range tr.Args {
+		tr.Args[i] = d.Value()
+	}
+
+	tr.Results = make([]interface{}, d.Length())
+	for i := range tr.Results {
+		tr.Results[i] = d.Value()
+	}
+
+	tr.Panic = d.Value()
+
+	return tr
+}
@@ -3,26 +3,61 @@ package cassete
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"go-vcr/track"
 	"go-vcr/tracklist"
 )
 
 var ErrTrackWasntRecorded = errors.New("Not recorded track can't be recorded to cassete")
+var ErrCasseteDisabled = errors.New("Cassete is disabled")
+var ErrUnknownMode = errors.New("Unknown cassete mode")
 
 type TrackMap map[track.Key]*tracklist.TrackList
 
 type Cassete struct {
-	id         uint64
-	tracks     TrackMap
-	isRecorded bool
+	id        uint64
+	tracks    TrackMap
+	mode      Mode
+	isModeSet bool
+
+	capturePanics bool
+	matchers      map[string]Matcher
+	latency       *latencySimulator
+	exhaustion    Exhaustion
+	keyExhaustion map[track.Key]Exhaustion
+	order         orderTracker
+	journal       *Journal
 
 	mutex sync.RWMutex
 }
 
-func New() *Cassete {
-	return &Cassete{
-		tracks: make(TrackMap),
+// lastID is seeded with the current time, so cassetes created
+// in different runs don't share IDs.
+var lastID = uint64(time.Now().UnixNano())
+
+func New(opts ...Option) *Cassete {
+	c := &Cassete{
+		id:            atomic.AddUint64(&lastID, 1),
+		tracks:        make(TrackMap),
+		mode:          ModeRecord,
+		matchers:      make(map[string]Matcher),
+		keyExhaustion: make(map[track.Key]Exhaustion),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithPanicCapture makes the cassete record the panics of the called functions.
+// The panic is raised again after the track is recorded and on its playback.
+func WithPanicCapture() Option {
+	return func(c *Cassete) {
+		c.capturePanics = true
 	}
 }
 
@@ -31,6 +66,15 @@ func (c *Cassete) ID() uint64 {
 }
 
 func (c *Cassete) Record(tracks ...*track.Track) error {
+	err := c.recordTracks(tracks)
+	if err != nil {
+		return err
+	}
+
+	return c.syncJournal()
+}
+
+func (c *Cassete) recordTracks(tracks []*track.Track) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -44,15 +88,26 @@ func (c *Cassete) Record(tracks ...*track.Track) error {
 	return nil
 }
 
+// record writes the track to the journal before adding it to the cassete,
+// so the track failed to be written isn't replayed.
 func (c *Cassete) record(tr *track.Track) error {
 	if !tr.IsRecorded() {
 		return ErrTrackWasntRecorded
 	}
 
+	tr.SetSeq(c.order.lastSeq + 1)
+	if c.journal != nil {
+		err := c.journal.writeTrack(c, tr.Key(), tr)
+		if err != nil {
+			return err
+		}
+	}
+
 	if _, ok := c.tracks[tr.Key()]; !ok {
 		c.tracks[tr.Key()] = tracklist.New()
 	}
 
+	c.order.add(tr.Key(), tr.Seq())
 	c.tracks[tr.Key()].Append(tr)
 
 	return nil
@@ -71,6 +126,9 @@ func (c *Cassete) Length() int {
 }
 
 func (c *Cassete) GetTrack(key track.Key) *track.Track {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
 	if trackList, ok := c.tracks[key]; ok {
 		return trackList.Next()
 	}
@@ -79,10 +137,88 @@ func (c *Cassete) GetTrack(key track.Key) *track.Track {
 }
 
 func (c *Cassete) Exec(tr *track.Track) error {
-	if c.isRecorded {
-		return c.GetTrack(tr.Key()).ResultsAs(tr).Playback()
+	if c.capturePanics {
+		tr.CapturePanic()
+	}
+
+	switch c.Mode() {
+	case ModeRecord:
+		return repanic(tr, c.recordNew(tr))
+	case ModeReplay, ModeRecordStale:
+		return c.playback(tr)
+	case ModeReplayOrRecord:
+		if trRecorded := c.nextTrack(tr); trRecorded != nil {
+			return c.replay(trRecorded, tr)
+		}
+
+		return repanic(tr, c.recordPlayed(tr))
+	case ModePassthrough:
+		return repanic(tr, tr.Record())
+	case ModeDisabled:
+		return ErrCasseteDisabled
+	}
+
+	return ErrUnknownMode
+}
+
+// repanic raises the panic captured by the track
+// after the track is successfully recorded.
+func repanic(tr *track.Track, err error) error {
+	if err == nil {
+		tr.Repanic()
+	}
+
+	return err
+}
+
+func (c *Cassete) playback(tr *track.Track) error {
+	trRecorded := c.nextTrack(tr)
+	if trRecorded == nil && c.isRecordOnExhaustion(tr.Key()) {
+		return repanic(tr, c.recordPlayed(tr))
+	}
+	if trRecorded == nil {
+		return c.playbackMissed(tr)
+	}
+
+	return c.replay(trRecorded, tr)
+}
+
+// playbackMissed records the call of the stale function again in ModeRecordStale
+// or returns the error describing why the track isn't found.
+func (c *Cassete) playbackMissed(tr *track.Track) error {
+	errStale := c.staleError(tr)
+	if errStale == nil {
+		return c.trackNotFoundError(tr)
 	}
 
+	if c.Mode() != ModeRecordStale {
+		return errStale
+	}
+
+	c.dropStale(errStale)
+
+	return repanic(tr, c.recordPlayed(tr))
+}
+
+func (c *Cassete) replay(trRecorded *track.Track, tr *track.Track) error {
+	trPlayed := trRecorded.ResultsAs(tr)
+
+	err := c.checkOrder(trRecorded)
+	if err != nil {
+		return err
+	}
+
+	if c.latency != nil {
+		err := c.latency.sleep(tr.Context(), trRecorded.Duration())
+		if err != nil {
+			return err
+		}
+	}
+
+	return trPlayed.Playback()
+}
+
+func (c *Cassete) recordNew(tr *track.Track) error {
 	err := tr.Record()
 	if err != nil {
 		return err
@@ -90,3 +226,34 @@ func (c *Cassete) Exec(tr *track.Track) error {
 
 	return c.Record(tr)
 }
+
+// recordPlayed records the track and marks it as already played,
+// so the next call with the same key doesn't replay it.
+func (c *Cassete) recordPlayed(tr *track.Track) error {
+	err := tr.Record()
+	if err != nil {
+		return err
+	}
+
+	err = c.appendPlayed(tr)
+	if err != nil {
+		return err
+	}
+
+	return c.syncJournal()
+}
+
+func (c *Cassete) appendPlayed(tr *track.Track) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.record(tr)
+	if err != nil {
+		return err
+	}
+
+	c.tracks[tr.Key()].Next()
+	c.order.markPlayed(tr.Seq())
+
+	return nil
+}
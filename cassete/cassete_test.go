@@ -1,12 +1,25 @@
 package cassete_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"gopkg.in/yaml.v2"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"go-vcr/cassete"
+	"go-vcr/internal/binrec"
 	"go-vcr/track"
 )
 
@@ -113,9 +126,13 @@ func TestCassete(t *testing.T) {
 				t.Run("Two tracks", func(t *testing.T) {
 					cas := cassete.New()
 
+					greet := func(name string) func() string {
+						return func() string { return "hey " + name }
+					}
+
 					names := []string{"Alice", "Mary"}
 					for _, name := range names {
-						fn := func() string { return "hey " + name }
+						fn := greet(name)
 
 						resultStr := ""
 						tr := track.New().Call(fn).ResultsIn(&resultStr)
@@ -126,7 +143,7 @@ func TestCassete(t *testing.T) {
 					}
 
 					for _, name := range names {
-						fn := func() string { return "hey girl" }
+						fn := greet("girl")
 						tr := track.New().Call(fn)
 
 						trNext := cas.GetTrack(tr.Key())
@@ -171,6 +188,33 @@ func TestCassete(t *testing.T) {
 				assert.Equal(t, 1, calledCount)
 			})
 
+			t.Run("Tracks dumped with previous key version are unsupported", func(t *testing.T) {
+				type request struct {
+					ID int64
+				}
+				fn := func(req request) string { return "found" }
+
+				cas := cassete.New()
+				resultStr := ""
+				cas.Exec(track.New().Call(fn).With(request{ID: 5}).ResultsIn(&resultStr))
+
+				path := filepath.Join(t.TempDir(), "cassete.yaml")
+				cas.Save(path)
+				dump, _ := os.ReadFile(path)
+				dumpOld := strings.ReplaceAll(string(dump), fmt.Sprintf("keyversion: %d", track.KeyVersion), "keyversion: 1")
+				assert.NotEqual(t, string(dump), dumpOld)
+				os.WriteFile(path, []byte(dumpOld), 0644)
+
+				_, err := cassete.Load(path)
+				assert.True(t, errors.Is(err, cassete.ErrUnsupportedVersion))
+				assert.True(t, errors.Is(err, track.ErrUnsupportedKeyVersion))
+
+				dump, _ = yaml.Marshal(cas)
+				dumpOld = strings.ReplaceAll(string(dump), fmt.Sprintf("keyversion: %d", track.KeyVersion), "keyversion: 1")
+				err = yaml.Unmarshal([]byte(dumpOld), cassete.New())
+				assert.True(t, errors.Is(err, track.ErrUnsupportedKeyVersion))
+			})
+
 			t.Run("Record or playback in dependence on cassete mode", func(t *testing.T) {
 				cas := cassete.New()
 
@@ -210,3 +254,1280 @@ func TestCassete(t *testing.T) {
 		})
 	})
 }
+
+func TestCasseteMode(t *testing.T) {
+	calledCount := 0
+	fn := func(argStr string) string {
+		calledCount++
+		return argStr
+	}
+	exec := func(cas *cassete.Cassete, argStr string) (string, error) {
+		resultStr := ""
+		tr := track.New().Call(fn).With(argStr).ResultsIn(&resultStr)
+		err := cas.Exec(tr)
+		return resultStr, err
+	}
+
+	t.Run("New cassete is in record mode", func(t *testing.T) {
+		cas := cassete.New()
+		assert.Equal(t, cassete.ModeRecord, cas.Mode())
+	})
+	t.Run("Mode can be set on creation", func(t *testing.T) {
+		cas := cassete.New(cassete.WithMode(cassete.ModePassthrough))
+		assert.Equal(t, cassete.ModePassthrough, cas.Mode())
+	})
+	t.Run("Mode can be switched at runtime", func(t *testing.T) {
+		cas := cassete.New()
+		cas.SetMode(cassete.ModeReplay)
+		assert.Equal(t, cassete.ModeReplay, cas.Mode())
+	})
+	t.Run("Restored cassete is switched to replay mode", func(t *testing.T) {
+		dump, _ := yaml.Marshal(cassete.New())
+
+		casRestored := cassete.New()
+		err := yaml.Unmarshal(dump, casRestored)
+		assert.Nil(t, err)
+		assert.Equal(t, cassete.ModeReplay, casRestored.Mode())
+	})
+	t.Run("Restored cassete keeps explicitly set mode", func(t *testing.T) {
+		dump, _ := yaml.Marshal(cassete.New())
+
+		casRestored := cassete.New(cassete.WithMode(cassete.ModeReplayOrRecord))
+		err := yaml.Unmarshal(dump, casRestored)
+		assert.Nil(t, err)
+		assert.Equal(t, cassete.ModeReplayOrRecord, casRestored.Mode())
+	})
+	t.Run("ReplayOrRecord replays existing and records missing tracks", func(t *testing.T) {
+		calledCount = 0
+		cas := cassete.New()
+
+		_, err := exec(cas, "old")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, calledCount)
+
+		cas.SetMode(cassete.ModeReplayOrRecord)
+
+		resultStr, err := exec(cas, "old")
+		assert.Nil(t, err)
+		assert.Equal(t, "old", resultStr)
+		assert.Equal(t, 1, calledCount)
+
+		for i := 0; i < 2; i++ {
+			resultStr, err = exec(cas, "new")
+			assert.Nil(t, err)
+			assert.Equal(t, "new", resultStr)
+		}
+		assert.Equal(t, 3, calledCount)
+		assert.Equal(t, 3, cas.Length())
+	})
+	t.Run("Passthrough calls the function and doesn't record", func(t *testing.T) {
+		calledCount = 0
+		cas := cassete.New(cassete.WithMode(cassete.ModePassthrough))
+
+		resultStr, err := exec(cas, "live")
+		assert.Nil(t, err)
+		assert.Equal(t, "live", resultStr)
+		assert.Equal(t, 1, calledCount)
+		assert.Equal(t, 0, cas.Length())
+	})
+	t.Run("Disabled cassete doesn't call the function", func(t *testing.T) {
+		calledCount = 0
+		cas := cassete.New(cassete.WithMode(cassete.ModeDisabled))
+
+		_, err := exec(cas, "live")
+		assert.Equal(t, cassete.ErrCasseteDisabled, err)
+		assert.Equal(t, 0, calledCount)
+	})
+}
+
+func TestCasseteTrackNotFound(t *testing.T) {
+	fn := func(argStr string) string { return argStr }
+	exec := func(cas *cassete.Cassete, argStr string) error {
+		resultStr := ""
+		return cas.Exec(track.New().Call(fn).With(argStr).ResultsIn(&resultStr))
+	}
+
+	t.Run("Replay of unknown key returns error with closest keys", func(t *testing.T) {
+		cas := cassete.New()
+		exec(cas, "Alice")
+		exec(cas, "Mary")
+		cas.SetMode(cassete.ModeReplay)
+
+		err := exec(cas, "Alina")
+		assert.True(t, errors.Is(err, cassete.ErrTrackNotFound))
+
+		var errNotFound *cassete.TrackNotFoundError
+		assert.True(t, errors.As(err, &errNotFound))
+		assert.Equal(t, "func(string) string", errNotFound.FnType)
+		assert.Equal(t, []interface{}{"Alina"}, errNotFound.Args)
+		assert.Equal(t, 0, errNotFound.Recorded)
+		assert.Len(t, errNotFound.Closest, 2)
+		assert.Equal(t, track.New().Call(fn).With("Alice").Key(), errNotFound.Closest[0].Key)
+		assert.Contains(t, err.Error(), `[string("Alice")]`)
+	})
+	t.Run("Closest keys are of the same function", func(t *testing.T) {
+		greet := func(argStr string) string { return "hey " + argStr }
+
+		cas := cassete.New()
+		exec(cas, "Alice")
+		resultStr := ""
+		cas.Exec(track.New().Call(greet).With("Alina").ResultsIn(&resultStr))
+		cas.SetMode(cassete.ModeReplay)
+
+		err := exec(cas, "Alina")
+		var errNotFound *cassete.TrackNotFoundError
+		assert.True(t, errors.As(err, &errNotFound))
+		assert.Len(t, errNotFound.Closest, 1)
+		assert.Equal(t, track.New().Call(fn).With("Alice").Key(), errNotFound.Closest[0].Key)
+	})
+	t.Run("Closest keys differing in long args", func(t *testing.T) {
+		prefix := strings.Repeat("x", 100)
+
+		cas := cassete.New()
+		exec(cas, prefix+"Alice"+strings.Repeat("y", 100))
+		exec(cas, prefix+"Bob")
+		cas.SetMode(cassete.ModeReplay)
+
+		err := exec(cas, prefix+"Alina")
+		var errNotFound *cassete.TrackNotFoundError
+		assert.True(t, errors.As(err, &errNotFound))
+		assert.Len(t, errNotFound.Closest, 2)
+		assert.Equal(t, track.New().Call(fn).With(prefix+"Bob").Key(), errNotFound.Closest[0].Key)
+	})
+	t.Run("Replay of exhausted key returns error with counts", func(t *testing.T) {
+		cas := cassete.New()
+		exec(cas, "Alice")
+		cas.SetMode(cassete.ModeReplay)
+
+		err := exec(cas, "Alice")
+		assert.Nil(t, err)
+
+		err = exec(cas, "Alice")
+		var errNotFound *cassete.TrackNotFoundError
+		assert.True(t, errors.As(err, &errNotFound))
+		assert.Equal(t, 1, errNotFound.Recorded)
+		assert.Equal(t, 1, errNotFound.Played)
+		assert.Empty(t, errNotFound.Closest)
+	})
+}
+
+func TestCasseteFile(t *testing.T) {
+	calledCount := 0
+	fn := func(argStr string) string {
+		calledCount++
+		return argStr
+	}
+	exec := func(cas *cassete.Cassete, argStr string) (string, error) {
+		resultStr := ""
+		err := cas.Exec(track.New().Call(fn).With(argStr).ResultsIn(&resultStr))
+		return resultStr, err
+	}
+
+	t.Run("Save and load cassete", func(t *testing.T) {
+		calledCount = 0
+		path := filepath.Join(t.TempDir(), "nested", "dir", "cassete.yaml")
+
+		cas := cassete.New()
+		exec(cas, "Hello world")
+
+		err := cas.Save(path)
+		assert.Nil(t, err)
+
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		assert.Len(t, entries, 1)
+
+		casLoaded, err := cassete.Load(path)
+		assert.Nil(t, err)
+		assert.Equal(t, cassete.ModeReplay, casLoaded.Mode())
+		assert.Equal(t, 1, casLoaded.Length())
+
+		resultStr, err := exec(casLoaded, "Hello world")
+		assert.Nil(t, err)
+		assert.Equal(t, "Hello world", resultStr)
+		assert.Equal(t, 1, calledCount)
+	})
+	t.Run("Save and load JSON cassete", func(t *testing.T) {
+		calledCount = 0
+		path := filepath.Join(t.TempDir(), "cassete.json")
+
+		cas := cassete.New()
+		exec(cas, "Hello world")
+
+		err := cas.Save(path)
+		assert.Nil(t, err)
+
+		data, _ := os.ReadFile(path)
+		assert.Contains(t, string(data), `"version":2`)
+
+		casLoaded, err := cassete.Load(path)
+		assert.Nil(t, err)
+		assert.Equal(t, cas.ID(), casLoaded.ID())
+
+		resultStr, err := exec(casLoaded, "Hello world")
+		assert.Nil(t, err)
+		assert.Equal(t, "Hello world", resultStr)
+		assert.Equal(t, 1, calledCount)
+	})
+	t.Run("Save and load gob cassete", func(t *testing.T) {
+		calledCount = 0
+		path := filepath.Join(t.TempDir(), "cassete.gob")
+
+		cas := cassete.New()
+		exec(cas, "Hello world")
+
+		err := cas.Save(path)
+		assert.Nil(t, err)
+
+		casLoaded, err := cassete.Load(path)
+		assert.Nil(t, err)
+		assert.Equal(t, cas.ID(), casLoaded.ID())
+
+		resultStr, err := exec(casLoaded, "Hello world")
+		assert.Nil(t, err)
+		assert.Equal(t, "Hello world", resultStr)
+		assert.Equal(t, 1, calledCount)
+	})
+	t.Run("Encode and decode with codec", func(t *testing.T) {
+		calledCount = 0
+		cas := cassete.New()
+		exec(cas, "Hello world")
+
+		for _, codec := range []cassete.Codec{cassete.CodecYAML, cassete.CodecJSON, cassete.CodecGob, cassete.CodecBinary} {
+			buf := new(bytes.Buffer)
+			err := codec.Encode(buf, cas)
+			assert.Nil(t, err)
+
+			casDecoded := cassete.New()
+			err = codec.Decode(buf, casDecoded)
+			assert.Nil(t, err)
+			assert.Equal(t, cas.ID(), casDecoded.ID())
+			assert.Equal(t, 1, casDecoded.Length())
+		}
+	})
+	t.Run("Save and load binary and gob cassetes", func(t *testing.T) {
+		type user struct {
+			Name string
+			Age  int
+		}
+		errFailed := errors.New("failed")
+		getUser := func(name string) (user, error) {
+			if name == "" {
+				return user{}, errFailed
+			}
+			return user{Name: name, Age: 42}, nil
+		}
+		execUser := func(cas *cassete.Cassete, name string) (user, error) {
+			var u user
+			var errResult error
+			err := cas.Exec(track.New().Call(getUser).With(name).ResultsIn(&u, &errResult))
+			assert.Nil(t, err)
+			return u, errResult
+		}
+
+		for _, name := range []string{"cassete.bin", "cassete.gob"} {
+			path := filepath.Join(t.TempDir(), name)
+
+			cas := cassete.New()
+			execUser(cas, "Alice")
+			execUser(cas, "Alice")
+			execUser(cas, "")
+
+			err := cas.Save(path)
+			assert.Nil(t, err)
+
+			casLoaded, err := cassete.Load(path)
+			assert.Nil(t, err)
+			assert.Equal(t, cas.ID(), casLoaded.ID())
+			assert.Equal(t, cas.Stats(), casLoaded.Stats())
+			assert.Equal(t, cassete.ModeReplay, casLoaded.Mode())
+
+			for i := 0; i < 2; i++ {
+				u, err := execUser(casLoaded, "Alice")
+				assert.Nil(t, err)
+				assert.Equal(t, user{Name: "Alice", Age: 42}, u)
+			}
+			_, err = execUser(casLoaded, "")
+			assert.Equal(t, "failed", err.Error(), name)
+		}
+	})
+	t.Run("Load of corrupted binary file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.bin")
+		cas := cassete.New()
+		exec(cas, "Hello world")
+		cas.Save(path)
+
+		data, _ := os.ReadFile(path)
+		os.WriteFile(path, data[:len(data)-1], 0644)
+
+		_, err := cassete.Load(path)
+		assert.True(t, errors.Is(err, cassete.ErrCasseteCorrupted))
+	})
+	t.Run("Load of binary file with corrupted args count", func(t *testing.T) {
+		buf := bytes.NewBufferString("VCRB")
+		e := binrec.NewEncoder(buf)
+		e.Uvarint(cassete.FormatVersion)
+		e.Uvarint(1)
+		e.Flush()
+		e.Ref("key")
+		e.Ref("name")
+		e.Varint(1)
+		e.Ref("func(string) string")
+		e.Uvarint(track.KeyVersion)
+		e.Bool(true)
+		e.Varint(0)
+		e.Uvarint(1 << 62)
+		e.Flush()
+
+		path := filepath.Join(t.TempDir(), "cassete.bin")
+		os.WriteFile(path, buf.Bytes(), 0644)
+
+		_, err := cassete.Load(path)
+		assert.True(t, errors.Is(err, cassete.ErrCasseteCorrupted))
+	})
+	t.Run("Registered codec is chosen by extension", func(t *testing.T) {
+		prevCodec := cassete.CodecFor("cassete.txt")
+		t.Cleanup(func() { cassete.RegisterCodec("txt", prevCodec) })
+
+		codec := &dumpCodec{}
+		cassete.RegisterCodec("TXT", codec)
+		assert.Equal(t, codec, cassete.CodecFor("cassete.txt"))
+		assert.Equal(t, cassete.CodecYAML, cassete.CodecFor("cassete.unknown"))
+
+		calledCount = 0
+		path := filepath.Join(t.TempDir(), "cassete.txt")
+		cas := cassete.New()
+		exec(cas, "Hello world")
+		err := cas.Save(path)
+		assert.Nil(t, err)
+
+		casLoaded, err := cassete.Load(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, codec.encoded)
+		assert.Equal(t, 1, codec.decoded)
+		assert.Equal(t, cas.ID(), casLoaded.ID())
+
+		resultStr, err := exec(casLoaded, "Hello world")
+		assert.Nil(t, err)
+		assert.Equal(t, "Hello world", resultStr)
+		assert.Equal(t, 1, calledCount)
+	})
+	t.Run("Save and load compressed cassete", func(t *testing.T) {
+		for _, name := range []string{"cassete.yaml.gz", "cassete.json.GZ", "cassete.bin.gz"} {
+			calledCount = 0
+			path := filepath.Join(t.TempDir(), name)
+
+			cas := cassete.New()
+			exec(cas, "Hello world")
+
+			err := cas.Save(path)
+			assert.Nil(t, err)
+
+			data, _ := os.ReadFile(path)
+			assert.Equal(t, []byte{0x1f, 0x8b}, data[:2])
+
+			casLoaded, err := cassete.Load(path)
+			assert.Nil(t, err)
+
+			resultStr, err := exec(casLoaded, "Hello world")
+			assert.Nil(t, err)
+			assert.Equal(t, "Hello world", resultStr)
+			assert.Equal(t, 1, calledCount)
+		}
+	})
+	t.Run("Compressed file is detected by magic bytes", func(t *testing.T) {
+		dir := t.TempDir()
+		cas := cassete.New()
+		exec(cas, "Hello world")
+		cas.Save(filepath.Join(dir, "cassete.yaml.gz"))
+		os.Rename(filepath.Join(dir, "cassete.yaml.gz"), filepath.Join(dir, "cassete.yaml"))
+
+		casLoaded, err := cassete.Load(filepath.Join(dir, "cassete.yaml"))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, casLoaded.Length())
+	})
+	t.Run("Load of truncated compressed file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.yaml.gz")
+		cas := cassete.New()
+		exec(cas, "Hello world")
+		cas.Save(path)
+
+		data, _ := os.ReadFile(path)
+		os.WriteFile(path, data[:len(data)/2], 0644)
+
+		_, err := cassete.Load(path)
+		assert.True(t, errors.Is(err, cassete.ErrCasseteCorrupted))
+	})
+	t.Run("Registered compressor is chosen by extension", func(t *testing.T) {
+		compressor := &countingCompressor{Compressor: cassete.CompressorGzip}
+		cassete.RegisterCompressor(".z", nil, compressor)
+
+		path := filepath.Join(t.TempDir(), "cassete.json.z")
+		cas := cassete.New()
+		exec(cas, "Hello world")
+		err := cas.Save(path)
+		assert.Nil(t, err)
+
+		casLoaded, err := cassete.Load(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, casLoaded.Length())
+		assert.Equal(t, 1, compressor.compressed)
+		assert.Equal(t, 1, compressor.decompressed)
+	})
+	t.Run("Saved file has format version", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.yaml")
+
+		err := cassete.New().Save(path)
+		assert.Nil(t, err)
+
+		data, _ := os.ReadFile(path)
+		assert.Contains(t, string(data), "version: 2")
+	})
+	t.Run("Load keeps mode set by options", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.yaml")
+		cassete.New().Save(path)
+
+		casLoaded, err := cassete.Load(path, cassete.WithMode(cassete.ModeReplayOrRecord))
+		assert.Nil(t, err)
+		assert.Equal(t, cassete.ModeReplayOrRecord, casLoaded.Mode())
+	})
+	t.Run("Load of missing file", func(t *testing.T) {
+		_, err := cassete.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.True(t, errors.Is(err, cassete.ErrCasseteNotFound))
+		assert.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+	t.Run("Load of corrupted file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.yaml")
+		os.WriteFile(path, []byte("version: [1"), 0644)
+
+		_, err := cassete.Load(path)
+		assert.True(t, errors.Is(err, cassete.ErrCasseteCorrupted))
+	})
+	t.Run("Load of file with keys of previous version", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.yaml")
+		os.WriteFile(path, []byte("version: 1"), 0644)
+
+		_, err := cassete.Load(path)
+		assert.True(t, errors.Is(err, cassete.ErrUnsupportedVersion))
+	})
+	t.Run("Decode of previous format with changed layout", func(t *testing.T) {
+		data := `{"version":1,"cassete":{"id":1,"tracks":{"key":{"Tracks":[{"Args":"Hello"}]}}}}`
+
+		path := filepath.Join(t.TempDir(), "cassete.json")
+		os.WriteFile(path, []byte(data), 0644)
+		_, err := cassete.Load(path)
+		assert.True(t, errors.Is(err, cassete.ErrUnsupportedVersion))
+
+		cas := cassete.New()
+		exec(cas, "Hello")
+		id := cas.ID()
+
+		err = cassete.CodecJSON.Decode(strings.NewReader(data), cas)
+		assert.True(t, errors.Is(err, cassete.ErrUnsupportedVersion))
+		assert.Equal(t, id, cas.ID())
+		assert.Equal(t, 1, cas.Length())
+	})
+	t.Run("Load of unsupported version", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.yaml")
+		os.WriteFile(path, []byte("version: 100"), 0644)
+
+		_, err := cassete.Load(path)
+		assert.True(t, errors.Is(err, cassete.ErrUnsupportedVersion))
+	})
+}
+
+// dumpCodec is the custom codec writing the cassete dump as YAML.
+type dumpCodec struct {
+	encoded int
+	decoded int
+}
+
+func (c *dumpCodec) Encode(w io.Writer, cas *cassete.Cassete) error {
+	c.encoded++
+
+	d, err := cas.Dump()
+	if err != nil {
+		return err
+	}
+
+	return yaml.NewEncoder(w).Encode(d)
+}
+
+func (c *dumpCodec) Decode(r io.Reader, cas *cassete.Cassete) error {
+	c.decoded++
+
+	var d cassete.Dump
+	err := yaml.NewDecoder(r).Decode(&d)
+	if err != nil {
+		return err
+	}
+
+	return cas.Restore(d)
+}
+
+type countingCompressor struct {
+	cassete.Compressor
+	compressed   int
+	decompressed int
+}
+
+func (c *countingCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	c.compressed++
+	return c.Compressor.Compress(w)
+}
+
+func (c *countingCompressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	c.decompressed++
+	return c.Compressor.Decompress(r)
+}
+
+func TestCassetePanicCapture(t *testing.T) {
+	calledCount := 0
+	fn := func(argStr string) string {
+		calledCount++
+		panic(argStr)
+	}
+	exec := func(cas *cassete.Cassete, argStr string) error {
+		resultStr := ""
+		return cas.Exec(track.New().Call(fn).With(argStr).ResultsIn(&resultStr))
+	}
+
+	cas := cassete.New(cassete.WithPanicCapture())
+	assert.PanicsWithValue(t, "boom", func() { exec(cas, "boom") })
+	assert.Equal(t, 1, cas.Length())
+
+	dump, _ := yaml.Marshal(cas)
+	casRestored := cassete.New()
+	err := yaml.Unmarshal(dump, casRestored)
+	assert.Nil(t, err)
+
+	assert.PanicsWithValue(t, "boom", func() { exec(casRestored, "boom") })
+	assert.Equal(t, 1, calledCount)
+}
+
+type request struct {
+	ID        string
+	Amount    float64
+	CreatedAt time.Time
+}
+
+func TestCasseteMatcher(t *testing.T) {
+	calledCount := 0
+	fn := func(req request, nonce int) float64 {
+		calledCount++
+		return req.Amount
+	}
+	exec := func(cas *cassete.Cassete, req request, nonce int) (float64, error) {
+		result := 0.0
+		err := cas.Exec(track.New().Call(fn).Named("pay").With(req, nonce).ResultsIn(&result))
+		return result, err
+	}
+
+	now := time.Date(2020, time.May, 1, 10, 0, 0, 0, time.UTC)
+	recorded := request{ID: "id-1", Amount: 10, CreatedAt: now}
+	actual := request{ID: "id-2", Amount: 10.001, CreatedAt: now.Add(time.Second)}
+
+	record := func(t *testing.T) *cassete.Cassete {
+		cas := cassete.New()
+		exec(cas, recorded, 1)
+
+		dump, _ := yaml.Marshal(cas)
+		casRestored := cassete.New()
+		err := yaml.Unmarshal(dump, casRestored)
+		assert.Nil(t, err)
+
+		return casRestored
+	}
+
+	t.Run("Exact key is required without matcher", func(t *testing.T) {
+		cas := record(t)
+
+		_, err := exec(cas, actual, 2)
+		assert.True(t, errors.Is(err, cassete.ErrTrackNotFound))
+	})
+	t.Run("Rules relax the comparison", func(t *testing.T) {
+		cas := record(t)
+		calledCount = 0
+		cas.SetMatcher("pay", cassete.MatchArgs(
+			cassete.IgnoreArg(1),
+			cassete.MatchRegexp("0.ID", regexp.MustCompile(`^id-\d+$`)),
+			cassete.NumericTolerance("0.Amount", 0.01),
+			cassete.TimeTolerance("0.CreatedAt", time.Minute),
+		))
+
+		result, err := exec(cas, actual, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, 10.0, result)
+		assert.Equal(t, 0, calledCount)
+
+		_, err = exec(cas, actual, 2)
+		assert.True(t, errors.Is(err, cassete.ErrTrackNotFound))
+	})
+	t.Run("Rules don't accept too different args", func(t *testing.T) {
+		cas := record(t)
+		cas.SetMatcher("pay", cassete.MatchArgs(
+			cassete.IgnoreArg(1),
+			cassete.IgnoreField("0.ID"),
+			cassete.NumericTolerance("0.Amount", 0.01),
+		))
+
+		_, err := exec(cas, actual, 2)
+		assert.True(t, errors.Is(err, cassete.ErrTrackNotFound))
+	})
+	t.Run("Custom matcher", func(t *testing.T) {
+		cas := cassete.New(cassete.WithMatcher("pay", func(recorded, actual []interface{}) bool {
+			return len(recorded) == len(actual)
+		}))
+		exec(cas, recorded, 1)
+		cas.SetMode(cassete.ModeReplay)
+
+		result, err := exec(cas, actual, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, 10.0, result)
+	})
+}
+
+func TestCasseteLatency(t *testing.T) {
+	const recordedDelay = 50 * time.Millisecond
+
+	fn := func(ctx context.Context) string {
+		time.Sleep(recordedDelay)
+		return "done"
+	}
+	exec := func(cas *cassete.Cassete, ctx context.Context) (time.Duration, error) {
+		resultStr := ""
+		startTime := time.Now()
+		err := cas.Exec(track.New().Call(fn).With(ctx).ResultsIn(&resultStr))
+		return time.Since(startTime), err
+	}
+	record := func(opts ...cassete.Option) *cassete.Cassete {
+		cas := cassete.New(opts...)
+		exec(cas, context.Background())
+		cas.SetMode(cassete.ModeReplay)
+		return cas
+	}
+
+	t.Run("Playback is instant by default", func(t *testing.T) {
+		cas := record()
+
+		elapsed, err := exec(cas, context.Background())
+		assert.Nil(t, err)
+		assert.Less(t, elapsed, recordedDelay)
+	})
+	t.Run("Playback takes the recorded duration", func(t *testing.T) {
+		cas := record(cassete.WithLatency(cassete.Latency{}))
+
+		elapsed, err := exec(cas, context.Background())
+		assert.Nil(t, err)
+		assert.GreaterOrEqual(t, elapsed, recordedDelay)
+	})
+	t.Run("Playback delay is scaled and capped", func(t *testing.T) {
+		cas := record(cassete.WithLatency(cassete.Latency{Multiplier: 10, Jitter: 0.5, Max: 5 * time.Millisecond}))
+
+		elapsed, err := exec(cas, context.Background())
+		assert.Nil(t, err)
+		assert.GreaterOrEqual(t, elapsed, 5*time.Millisecond)
+		assert.Less(t, elapsed, recordedDelay)
+	})
+	t.Run("Playback delay respects context", func(t *testing.T) {
+		cas := record(cassete.WithLatency(cassete.Latency{Multiplier: 10}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+
+		elapsed, err := exec(cas, ctx)
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Less(t, elapsed, recordedDelay)
+	})
+}
+
+type fakeTB struct {
+	errors []string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCasseteUnplayed(t *testing.T) {
+	fn := func(argStr string) string { return argStr }
+	exec := func(cas *cassete.Cassete, argStr string) error {
+		resultStr := ""
+		return cas.Exec(track.New().Call(fn).With(argStr).ResultsIn(&resultStr))
+	}
+	keyOf := func(argStr string) track.Key {
+		return track.New().Call(fn).With(argStr).Key()
+	}
+
+	cas := cassete.New()
+	exec(cas, "Alice")
+	exec(cas, "Alice")
+	exec(cas, "Mary")
+	cas.SetMode(cassete.ModeReplay)
+
+	exec(cas, "Alice")
+
+	t.Run("Stats per key", func(t *testing.T) {
+		assert.Equal(t, []cassete.TrackStat{
+			{Key: keyOf("Alice"), Recorded: 2, Played: 1},
+			{Key: keyOf("Mary"), Recorded: 1, Played: 0},
+		}, cas.Stats())
+	})
+	t.Run("Unplayed tracks are reported", func(t *testing.T) {
+		tb := &fakeTB{}
+		assert.False(t, cas.AssertAllPlayed(tb))
+		assert.Len(t, tb.errors, 1)
+		assert.Contains(t, tb.errors[0], string(keyOf("Alice"))+": 1 of 2")
+		assert.Contains(t, tb.errors[0], string(keyOf("Mary"))+": 1 of 1")
+	})
+	t.Run("All played", func(t *testing.T) {
+		exec(cas, "Alice")
+		exec(cas, "Mary")
+
+		tb := &fakeTB{}
+		assert.True(t, cas.AssertAllPlayed(tb))
+		assert.Empty(t, tb.errors)
+		assert.Empty(t, cas.Unplayed())
+	})
+	t.Run("Recorded tracks aren't checked while recording", func(t *testing.T) {
+		cas := cassete.New()
+		exec(cas, "Alice")
+
+		tb := &fakeTB{}
+		assert.True(t, cas.AssertAllPlayed(tb))
+		assert.Empty(t, tb.errors)
+
+		cas.SetMode(cassete.ModeReplay)
+		assert.False(t, cas.AssertAllPlayed(tb))
+	})
+	t.Run("Tracks recorded on replay are played", func(t *testing.T) {
+		cas := cassete.New(cassete.WithMode(cassete.ModeReplayOrRecord))
+		exec(cas, "Alice")
+
+		assert.True(t, cas.AssertAllPlayed(&fakeTB{}))
+	})
+}
+
+func TestCasseteExhaustion(t *testing.T) {
+	calledCount := 0
+	fn := func() int {
+		calledCount++
+		return calledCount
+	}
+	exec := func(cas *cassete.Cassete) (int, error) {
+		result := 0
+		err := cas.Exec(track.New().Call(fn).ResultsIn(&result))
+		return result, err
+	}
+	record := func(opts ...cassete.Option) *cassete.Cassete {
+		calledCount = 0
+		cas := cassete.New(opts...)
+		exec(cas)
+		exec(cas)
+		cas.SetMode(cassete.ModeReplay)
+		return cas
+	}
+	replay := func(cas *cassete.Cassete, n int) []int {
+		results := make([]int, 0, n)
+		for i := 0; i < n; i++ {
+			result, err := exec(cas)
+			assert.Nil(t, err)
+			results = append(results, result)
+		}
+		return results
+	}
+
+	t.Run("Strict by default", func(t *testing.T) {
+		cas := record()
+		replay(cas, 2)
+
+		_, err := exec(cas)
+		assert.True(t, errors.Is(err, cassete.ErrTrackNotFound))
+	})
+	t.Run("Repeat last", func(t *testing.T) {
+		cas := record(cassete.WithExhaustion(cassete.ExhaustRepeatLast))
+		assert.Equal(t, []int{1, 2, 2, 2}, replay(cas, 4))
+		assert.True(t, cas.AssertAllPlayed(&fakeTB{}))
+	})
+	t.Run("Repeat last concurrently", func(t *testing.T) {
+		cas := record(cassete.WithExhaustion(cassete.ExhaustRepeatLast))
+		replay(cas, 2)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result, err := exec(cas)
+				assert.Nil(t, err)
+				assert.Equal(t, 2, result)
+			}()
+		}
+		wg.Wait()
+	})
+	t.Run("Cycle", func(t *testing.T) {
+		cas := record(cassete.WithExhaustion(cassete.ExhaustCycle))
+		assert.Equal(t, []int{1, 2, 1, 2, 1}, replay(cas, 5))
+		assert.True(t, cas.AssertAllPlayed(&fakeTB{}))
+	})
+	t.Run("Record", func(t *testing.T) {
+		cas := record(cassete.WithExhaustion(cassete.ExhaustRecord))
+		assert.Equal(t, []int{1, 2, 3, 4}, replay(cas, 4))
+		assert.Equal(t, 4, cas.Length())
+		assert.Equal(t, 4, calledCount)
+	})
+	t.Run("Policy per key", func(t *testing.T) {
+		key := track.New().Call(fn).Key()
+		cas := record(cassete.WithExhaustion(cassete.ExhaustCycle))
+		cas.SetKeyExhaustion(key, cassete.ExhaustRepeatLast)
+		assert.Equal(t, []int{1, 2, 2}, replay(cas, 3))
+	})
+}
+
+func TestCasseteOrder(t *testing.T) {
+	login := func() string { return "login" }
+	fetch := func() string { return "fetch" }
+	logout := func() string { return "logout" }
+	exec := func(cas *cassete.Cassete, fn func() string) error {
+		resultStr := ""
+		return cas.Exec(track.New().Call(fn).ResultsIn(&resultStr))
+	}
+	record := func(t *testing.T, opts ...cassete.Option) *cassete.Cassete {
+		cas := cassete.New()
+		for _, fn := range []func() string{login, fetch, logout} {
+			exec(cas, fn)
+		}
+
+		dump, _ := yaml.Marshal(cas)
+		assert.Contains(t, string(dump), "seq: 3")
+
+		casRestored := cassete.New(opts...)
+		err := yaml.Unmarshal(dump, casRestored)
+		assert.Nil(t, err)
+
+		return casRestored
+	}
+
+	t.Run("Order is ignored by default", func(t *testing.T) {
+		cas := record(t)
+		assert.Nil(t, exec(cas, fetch))
+		assert.Nil(t, exec(cas, login))
+	})
+	t.Run("Recorded order passes strict check", func(t *testing.T) {
+		cas := record(t, cassete.WithOrder(cassete.OrderStrict))
+		for _, fn := range []func() string{login, fetch, logout} {
+			assert.Nil(t, exec(cas, fn))
+		}
+	})
+	t.Run("Strict order fails out of order call", func(t *testing.T) {
+		cas := record(t, cassete.WithOrder(cassete.OrderStrict))
+		assert.Nil(t, exec(cas, login))
+
+		err := exec(cas, logout)
+		assert.True(t, errors.Is(err, cassete.ErrOrderViolation))
+
+		var errOrder *cassete.OrderError
+		assert.True(t, errors.As(err, &errOrder))
+		assert.Equal(t, 3, errOrder.Seq)
+		assert.Equal(t, 2, errOrder.ExpectedSeq)
+		assert.Equal(t, track.New().Call(fetch).Key(), errOrder.ExpectedKey)
+	})
+	t.Run("Lenient order reports violations", func(t *testing.T) {
+		cas := record(t, cassete.WithOrder(cassete.OrderLenient))
+		for _, fn := range []func() string{fetch, login, logout} {
+			assert.Nil(t, exec(cas, fn))
+		}
+
+		violations := cas.OrderViolations()
+		assert.Len(t, violations, 1)
+		assert.Equal(t, 2, violations[0].Seq)
+		assert.Equal(t, 1, violations[0].ExpectedSeq)
+	})
+}
+
+func TestCasseteStale(t *testing.T) {
+	calledCount := 0
+	getUserOld := func(id int) string { return "old" }
+	getUser := func(id int, full bool) string {
+		calledCount++
+		return "new"
+	}
+	name := track.New().Call(getUser).Name()
+
+	record := func(t *testing.T, opts ...cassete.Option) *cassete.Cassete {
+		cas := cassete.New()
+		for _, id := range []int{1, 2} {
+			resultStr := ""
+			cas.Exec(track.New().Call(getUserOld).Named(name).With(id).ResultsIn(&resultStr))
+		}
+
+		dump, _ := yaml.Marshal(cas)
+		assert.Contains(t, string(dump), "func: func(int) string")
+
+		casRestored := cassete.New(opts...)
+		err := yaml.Unmarshal(dump, casRestored)
+		assert.Nil(t, err)
+
+		return casRestored
+	}
+	exec := func(cas *cassete.Cassete) (string, error) {
+		resultStr := ""
+		err := cas.Exec(track.New().Call(getUser).With(1, true).ResultsIn(&resultStr))
+		return resultStr, err
+	}
+
+	t.Run("Stale track is detected on replay", func(t *testing.T) {
+		cas := record(t)
+
+		_, err := exec(cas)
+		assert.True(t, errors.Is(err, cassete.ErrStaleTrack))
+
+		var errStale *cassete.StaleTrackError
+		assert.True(t, errors.As(err, &errStale))
+		assert.Equal(t, "func(int) string", errStale.RecordedFnType)
+		assert.Equal(t, "func(int, bool) string", errStale.FnType)
+		assert.Len(t, errStale.Keys, 2)
+	})
+	t.Run("Stale track is detected after load", func(t *testing.T) {
+		cas := record(t)
+
+		err := cas.CheckStale(getUser)
+		assert.True(t, errors.Is(err, cassete.ErrStaleTrack))
+
+		err = cas.CheckStale(func() {})
+		assert.Nil(t, err)
+	})
+	t.Run("Stale tracks are recorded again", func(t *testing.T) {
+		calledCount = 0
+		cas := record(t, cassete.WithMode(cassete.ModeRecordStale))
+
+		resultStr, err := exec(cas)
+		assert.Nil(t, err)
+		assert.Equal(t, "new", resultStr)
+		assert.Equal(t, 1, calledCount)
+		assert.Equal(t, 1, cas.Length())
+		assert.Nil(t, cas.CheckStale(getUser))
+	})
+	t.Run("Order is kept after stale tracks are recorded again", func(t *testing.T) {
+		greet := func(name string) string { return "hey " + name }
+		execGreet := func(cas *cassete.Cassete) error {
+			resultStr := ""
+			return cas.Exec(track.New().Call(greet).With("Alice").ResultsIn(&resultStr))
+		}
+
+		cas := cassete.New()
+		resultStr := ""
+		cas.Exec(track.New().Call(getUserOld).Named(name).With(1).ResultsIn(&resultStr))
+		execGreet(cas)
+
+		dump, _ := yaml.Marshal(cas)
+		casRestored := cassete.New(cassete.WithMode(cassete.ModeRecordStale), cassete.WithOrder(cassete.OrderStrict))
+		yaml.Unmarshal(dump, casRestored)
+
+		_, err := exec(casRestored)
+		assert.Nil(t, err)
+		assert.Nil(t, execGreet(casRestored))
+		assert.Empty(t, casRestored.OrderViolations())
+	})
+}
+
+func newBenchmarkCassete(b *testing.B) *cassete.Cassete {
+	type user struct {
+		ID    int
+		Name  string
+		Email string
+		Tags  []string
+	}
+	getUser := func(id int) (user, error) {
+		return user{
+			ID:    id,
+			Name:  fmt.Sprintf("user %d", id),
+			Email: fmt.Sprintf("user%d@example.com", id),
+			Tags:  []string{"admin", "staff"},
+		}, nil
+	}
+
+	cas := cassete.New()
+	for i := 0; i < 1000; i++ {
+		var u user
+		var errResult error
+		err := cas.Exec(track.New().Call(getUser).With(i%100).ResultsIn(&u, &errResult))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return cas
+}
+
+var benchmarkCodecs = []struct {
+	name string
+	ext  string
+}{
+	{name: "YAML", ext: ".yaml"},
+	{name: "JSON", ext: ".json"},
+	{name: "Gob", ext: ".gob"},
+	{name: "Binary", ext: ".bin"},
+}
+
+func BenchmarkCasseteSave(b *testing.B) {
+	cas := newBenchmarkCassete(b)
+
+	for _, codec := range benchmarkCodecs {
+		b.Run(codec.name, func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "cassete"+codec.ext)
+			for i := 0; i < b.N; i++ {
+				err := cas.Save(path)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			info, _ := os.Stat(path)
+			b.ReportMetric(float64(info.Size()), "file-bytes")
+		})
+	}
+}
+
+func BenchmarkCasseteLoad(b *testing.B) {
+	cas := newBenchmarkCassete(b)
+
+	for _, codec := range benchmarkCodecs {
+		b.Run(codec.name, func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "cassete"+codec.ext)
+			err := cas.Save(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := cassete.Load(path)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCasseteTrackNotFound(b *testing.B) {
+	fn := func(argStr string) string { return argStr }
+	exec := func(cas *cassete.Cassete, argStr string) error {
+		resultStr := ""
+		return cas.Exec(track.New().Call(fn).With(argStr).ResultsIn(&resultStr))
+	}
+
+	cas := cassete.New()
+	padding := strings.Repeat("x", 300)
+	for i := 0; i < 20000; i++ {
+		exec(cas, fmt.Sprintf("%s %d", padding, i))
+	}
+	cas.SetMode(cassete.ModeReplay)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := exec(cas, padding+" missing")
+		if !errors.Is(err, cassete.ErrTrackNotFound) {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCasseteJournal(t *testing.T) {
+	calledCount := 0
+	fn := func(argStr string) string {
+		calledCount++
+		return argStr
+	}
+	exec := func(cas *cassete.Cassete, argStr string) (string, error) {
+		resultStr := ""
+		err := cas.Exec(track.New().Call(fn).With(argStr).ResultsIn(&resultStr))
+		return resultStr, err
+	}
+
+	t.Run("Rebuild cassete from journal", func(t *testing.T) {
+		for _, policy := range []cassete.JournalOption{
+			cassete.WithSync(cassete.SyncOnClose),
+			cassete.WithSync(cassete.SyncEveryTrack),
+			cassete.WithSyncInterval(time.Hour),
+		} {
+			calledCount = 0
+			path := filepath.Join(t.TempDir(), "nested", "cassete.jsonl")
+
+			journal, err := cassete.OpenJournal(path, policy)
+			assert.Nil(t, err)
+
+			cas := cassete.New(cassete.WithJournal(journal))
+			exec(cas, "Hello")
+			exec(cas, "world")
+			exec(cas, "Hello")
+
+			// The journal isn't closed as if the process crashed.
+			casLoaded, err := cassete.LoadJournal(path)
+			assert.Nil(t, err)
+			assert.Equal(t, cas.ID(), casLoaded.ID())
+			assert.Equal(t, cas.Stats(), casLoaded.Stats())
+			assert.Equal(t, cassete.ModeReplay, casLoaded.Mode())
+
+			casLoaded.SetOrder(cassete.OrderStrict)
+			for _, argStr := range []string{"Hello", "world", "Hello"} {
+				resultStr, err := exec(casLoaded, argStr)
+				assert.Nil(t, err)
+				assert.Equal(t, argStr, resultStr)
+			}
+			assert.Equal(t, 3, calledCount)
+
+			assert.Nil(t, journal.Close())
+		}
+	})
+	t.Run("Truncated last line is skipped", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.jsonl")
+		journal, _ := cassete.OpenJournal(path)
+		cas := cassete.New(cassete.WithJournal(journal))
+		exec(cas, "Hello")
+		exec(cas, "world")
+		journal.Close()
+
+		data, _ := os.ReadFile(path)
+		os.WriteFile(path, data[:len(data)-10], 0644)
+
+		casLoaded, err := cassete.LoadJournal(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, casLoaded.Length())
+	})
+	t.Run("Broken line in the middle is corrupted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.jsonl")
+		journal, _ := cassete.OpenJournal(path)
+		cas := cassete.New(cassete.WithJournal(journal))
+		exec(cas, "Hello")
+		journal.Close()
+
+		data, _ := os.ReadFile(path)
+		os.WriteFile(path, append([]byte("{broken\n"), data...), 0644)
+
+		_, err := cassete.LoadJournal(path)
+		assert.True(t, errors.Is(err, cassete.ErrCasseteCorrupted))
+	})
+	t.Run("Reopened journal is appended", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.jsonl")
+
+		journal, _ := cassete.OpenJournal(path)
+		cas := cassete.New(cassete.WithJournal(journal))
+		exec(cas, "Hello")
+		journal.Close()
+
+		journal, _ = cassete.OpenJournal(path)
+		cas.SetJournal(journal)
+		exec(cas, "world")
+		journal.Close()
+
+		data, _ := os.ReadFile(path)
+		assert.Equal(t, 1, bytes.Count(data, []byte(`"version"`)))
+
+		casLoaded, err := cassete.LoadJournal(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, casLoaded.Length())
+	})
+	t.Run("Record to closed journal", func(t *testing.T) {
+		journal, _ := cassete.OpenJournal(filepath.Join(t.TempDir(), "cassete.jsonl"))
+		assert.Nil(t, journal.Close())
+		assert.True(t, errors.Is(journal.Close(), cassete.ErrJournalClosed))
+
+		cas := cassete.New(cassete.WithJournal(journal))
+		_, err := exec(cas, "Hello")
+		assert.True(t, errors.Is(err, cassete.ErrJournalClosed))
+		assert.Equal(t, 0, cas.Length())
+	})
+	t.Run("Reopened journal truncates partially written line", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.jsonl")
+
+		journal, _ := cassete.OpenJournal(path)
+		cas := cassete.New(cassete.WithJournal(journal))
+		exec(cas, "Hello")
+		journal.Close()
+
+		// The crash left the line partially written.
+		f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		f.WriteString(`{"key":"bro`)
+		f.Close()
+
+		journal, err := cassete.OpenJournal(path)
+		assert.Nil(t, err)
+		cas.SetJournal(journal)
+		exec(cas, "world")
+		journal.Close()
+
+		casLoaded, err := cassete.LoadJournal(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, casLoaded.Length())
+	})
+	t.Run("Reopened journal with partially written header", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.jsonl")
+		os.WriteFile(path, []byte(`{"vers`), 0644)
+
+		journal, err := cassete.OpenJournal(path)
+		assert.Nil(t, err)
+		cas := cassete.New(cassete.WithJournal(journal))
+		exec(cas, "Hello")
+		journal.Close()
+
+		casLoaded, err := cassete.LoadJournal(path)
+		assert.Nil(t, err)
+		assert.Equal(t, cas.ID(), casLoaded.ID())
+		assert.Equal(t, 1, casLoaded.Length())
+	})
+	t.Run("Journal of another cassete isn't appended", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.jsonl")
+
+		journal, _ := cassete.OpenJournal(path)
+		exec(cassete.New(cassete.WithJournal(journal)), "Hello")
+		journal.Close()
+
+		journal, _ = cassete.OpenJournal(path)
+		defer journal.Close()
+		cas := cassete.New(cassete.WithJournal(journal))
+		_, err := exec(cas, "world")
+		assert.True(t, errors.Is(err, cassete.ErrJournalOfOtherCassete))
+		assert.Equal(t, 0, cas.Length())
+	})
+	t.Run("Cassete loaded from journal continues it", func(t *testing.T) {
+		calledCount = 0
+		path := filepath.Join(t.TempDir(), "cassete.jsonl")
+
+		journal, _ := cassete.OpenJournal(path)
+		cas := cassete.New(cassete.WithJournal(journal))
+		exec(cas, "Hello")
+		exec(cas, "world")
+		journal.Close()
+
+		casLoaded, err := cassete.LoadJournal(path, cassete.WithMode(cassete.ModeRecord))
+		assert.Nil(t, err)
+		journal, _ = cassete.OpenJournal(path)
+		casLoaded.SetJournal(journal)
+		_, err = exec(casLoaded, "again")
+		assert.Nil(t, err)
+		journal.Close()
+
+		casLoaded, err = cassete.LoadJournal(path, cassete.WithOrder(cassete.OrderStrict))
+		assert.Nil(t, err)
+		assert.Equal(t, cas.ID(), casLoaded.ID())
+		for _, argStr := range []string{"Hello", "world", "again"} {
+			_, err := exec(casLoaded, argStr)
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, 3, calledCount)
+	})
+	t.Run("Journal of unsupported version isn't opened", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cassete.jsonl")
+		os.WriteFile(path, []byte(`{"version":1,"id":1}`+"\n"), 0644)
+
+		_, err := cassete.OpenJournal(path)
+		assert.True(t, errors.Is(err, cassete.ErrUnsupportedVersion))
+	})
+	t.Run("Load of missing journal", func(t *testing.T) {
+		_, err := cassete.LoadJournal(filepath.Join(t.TempDir(), "missing.jsonl"))
+		assert.True(t, errors.Is(err, cassete.ErrCasseteNotFound))
+	})
+}
@@ -0,0 +1,121 @@
+package cassete
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Codec encodes the cassete to a stream and decodes it from a stream.
+// Decode fills the given cassete, so the options it was created with are kept.
+// The custom codecs encode the cassete Dump and Restore the decoded one.
+type Codec interface {
+	Encode(w io.Writer, c *Cassete) error
+	Decode(r io.Reader, c *Cassete) error
+}
+
+var (
+	CodecYAML Codec = &fileCodec{encode: encodeYAML, unmarshal: yaml.Unmarshal}
+	CodecJSON Codec = &fileCodec{encode: encodeJSON, unmarshal: json.Unmarshal}
+	CodecGob  Codec = gobCodec{}
+	// CodecBinary is the compact and fast to load codec for the large cassetes.
+	CodecBinary Codec = binaryCodec{}
+)
+
+var codecs = struct {
+	byExt map[string]Codec
+	mutex sync.RWMutex
+}{
+	byExt: map[string]Codec{
+		".yaml": CodecYAML,
+		".yml":  CodecYAML,
+		".json": CodecJSON,
+		".gob":  CodecGob,
+		".bin":  CodecBinary,
+	},
+}
+
+// RegisterCodec makes Load and Save use the codec for the files with the extension.
+// The extension is matched ignoring case and replaces the codec registered before.
+func RegisterCodec(ext string, codec Codec) {
+	codecs.mutex.Lock()
+	defer codecs.mutex.Unlock()
+
+	codecs.byExt[normalizeExt(ext)] = codec
+}
+
+// CodecFor chooses the codec of the file by its extension, YAML is the default.
+// The extension of the compressor is skipped, so cassete.json.gz is JSON.
+func CodecFor(path string) Codec {
+	_, path = compressorFor(path)
+
+	codecs.mutex.RLock()
+	defer codecs.mutex.RUnlock()
+
+	if codec, ok := codecs.byExt[normalizeExt(filepath.Ext(path))]; ok {
+		return codec
+	}
+
+	return CodecYAML
+}
+
+func normalizeExt(ext string) string {
+	return "." + strings.ToLower(strings.TrimPrefix(ext, "."))
+}
+
+// fileCodec encodes the cassete with the header carrying the format version.
+// The version is decoded first, so the cassete is decoded
+// only from the files of the current format.
+type fileCodec struct {
+	encode    func(io.Writer, interface{}) error
+	unmarshal func([]byte, interface{}) error
+}
+
+// fileHeader is the part of casseteFile decoded before the cassete.
+type fileHeader struct {
+	Version int `json:"version"`
+}
+
+func (f *fileCodec) Encode(w io.Writer, c *Cassete) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return f.encode(w, casseteFile{Version: FormatVersion, Cassete: c})
+}
+
+func (f *fileCodec) Decode(r io.Reader, c *Cassete) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var header fileHeader
+	err = f.unmarshal(data, &header)
+	if err != nil {
+		return err
+	}
+	if header.Version != FormatVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedVersion, header.Version)
+	}
+
+	return f.unmarshal(data, &casseteFile{Cassete: c})
+}
+
+func encodeYAML(w io.Writer, v interface{}) error {
+	encoder := yaml.NewEncoder(w)
+	err := encoder.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
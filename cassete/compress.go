@@ -0,0 +1,93 @@
+package cassete
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Compressor wraps the stream of the encoded cassete.
+// Compress returns the writer which is closed after the cassete is encoded,
+// Decompress returns the reader which is closed after the cassete is decoded.
+type Compressor interface {
+	Compress(w io.Writer) (io.WriteCloser, error)
+	Decompress(r io.Reader) (io.ReadCloser, error)
+}
+
+var CompressorGzip Compressor = gzipCompressor{}
+
+type compressorEntry struct {
+	compressor Compressor
+	magic      []byte
+}
+
+var compressors = struct {
+	byExt map[string]compressorEntry
+	mutex sync.RWMutex
+}{
+	byExt: map[string]compressorEntry{
+		".gz": {compressor: CompressorGzip, magic: []byte{0x1f, 0x8b}},
+	},
+}
+
+// RegisterCompressor makes Load and Save compress the files with the extension,
+// the codec is chosen then by the extension before it: cassete.yaml.gz is
+// the gzipped YAML. The files starting with magic are decompressed on Load
+// whatever extension they have, the empty magic disables the detection.
+func RegisterCompressor(ext string, magic []byte, compressor Compressor) {
+	compressors.mutex.Lock()
+	defer compressors.mutex.Unlock()
+
+	compressors.byExt[normalizeExt(ext)] = compressorEntry{
+		compressor: compressor,
+		magic:      append([]byte(nil), magic...),
+	}
+}
+
+// compressorFor chooses the compressor of the file by its extension
+// and returns the path without the extension of the compressor.
+// It returns nil and the path as is for not compressed files.
+func compressorFor(path string) (Compressor, string) {
+	compressors.mutex.RLock()
+	defer compressors.mutex.RUnlock()
+
+	ext := filepath.Ext(path)
+	if entry, ok := compressors.byExt[normalizeExt(ext)]; ok {
+		return entry.compressor, strings.TrimSuffix(path, ext)
+	}
+
+	return nil, path
+}
+
+// sniffCompressor chooses the compressor by the magic bytes the stream starts with.
+func sniffCompressor(r *bufio.Reader) Compressor {
+	compressors.mutex.RLock()
+	defer compressors.mutex.RUnlock()
+
+	for _, entry := range compressors.byExt {
+		if len(entry.magic) == 0 {
+			continue
+		}
+
+		head, _ := r.Peek(len(entry.magic))
+		if bytes.Equal(head, entry.magic) {
+			return entry.compressor
+		}
+	}
+
+	return nil
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
@@ -0,0 +1,65 @@
+package cassete
+
+import (
+	"sort"
+
+	"go-vcr/track"
+	"go-vcr/tracklist"
+)
+
+// Dump is the codec-neutral view of the cassete, the custom codecs
+// encode it and restore the decoded one, see RegisterCodec.
+// The tracks are sorted by key and keep the recorded order for the same key.
+type Dump struct {
+	ID     uint64
+	Tracks []track.Dump
+}
+
+func (c *Cassete) Dump() (Dump, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	keys := make([]track.Key, 0, len(c.tracks))
+	for key := range c.tracks {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	d := Dump{ID: c.id}
+	for _, key := range keys {
+		for _, tr := range c.tracks[key].Tracks() {
+			trDump, err := tr.Dump()
+			if err != nil {
+				return Dump{}, err
+			}
+			d.Tracks = append(d.Tracks, trDump)
+		}
+	}
+
+	return d, nil
+}
+
+// Restore sets the tracks of the dump, the cassete replays them
+// unless the mode was set explicitly.
+func (c *Cassete) Restore(d Dump) error {
+	tracks := make(TrackMap)
+	for i := range d.Tracks {
+		tr := track.New()
+		err := tr.Restore(d.Tracks[i])
+		if err != nil {
+			return err
+		}
+
+		if _, ok := tracks[tr.Key()]; !ok {
+			tracks[tr.Key()] = tracklist.New()
+		}
+		tracks[tr.Key()].Append(tr)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.restore(d.ID, tracks)
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package cassete
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go-vcr/track"
+)
+
+var ErrTrackNotFound = errors.New("Track not found in cassete")
+
+const closestKeysLimit = 3
+
+// distanceLimit is the max length of the differing parts of the keys compared
+// by distance, the rest is counted as changed, so a miss costs little
+// on the cassetes with many long keys.
+const distanceLimit = 32
+
+type ClosestKey struct {
+	Key  track.Key
+	Diff string
+}
+
+type TrackNotFoundError struct {
+	Key      track.Key
+	Name     string
+	FnType   string
+	Args     []interface{}
+	Recorded int
+	Played   int
+	Closest  []ClosestKey
+}
+
+func (e *TrackNotFoundError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s: key %s", ErrTrackNotFound, e.Key)
+	fmt.Fprintf(&b, "\n\tfunc: %s %s\n\targs: %v", e.Name, e.FnType, e.Args)
+	fmt.Fprintf(&b, "\n\trecorded: %d, played: %d", e.Recorded, e.Played)
+
+	for _, closest := range e.Closest {
+		fmt.Fprintf(&b, "\n\tclosest recorded key:\n%s", closest.Diff)
+	}
+
+	return b.String()
+}
+
+func (e *TrackNotFoundError) Is(target error) bool {
+	return target == ErrTrackNotFound
+}
+
+func (c *Cassete) trackNotFoundError(tr *track.Track) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	key := tr.Key()
+	err := &TrackNotFoundError{
+		Key:    key,
+		Name:   tr.Name(),
+		FnType: tr.FnType(),
+		Args:   tr.KeyArgs(),
+	}
+
+	if trackList, ok := c.tracks[key]; ok {
+		err.Recorded = trackList.Length()
+		err.Played = trackList.Played()
+		return err
+	}
+
+	err.Closest = c.closestKeys(tr, closestKeysLimit)
+
+	return err
+}
+
+// closestKeys finds the recorded keys closest to the key of the track.
+// Only the keys of the same function are compared if there are any.
+func (c *Cassete) closestKeys(tr *track.Track, limit int) []ClosestKey {
+	key, name, fnType := tr.Key(), tr.Name(), tr.FnType()
+
+	keys := make([]track.Key, 0, len(c.tracks))
+	for recordedKey, trackList := range c.tracks {
+		trRecorded := trackList.Last()
+		if trRecorded != nil && trRecorded.Name() == name && trRecorded.FnType() == fnType {
+			keys = append(keys, recordedKey)
+		}
+	}
+	if len(keys) == 0 {
+		for recordedKey := range c.tracks {
+			keys = append(keys, recordedKey)
+		}
+	}
+
+	distances := make(map[track.Key]int, len(keys))
+	for _, recordedKey := range keys {
+		distances[recordedKey] = distance(string(key), string(recordedKey))
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if distances[keys[i]] != distances[keys[j]] {
+			return distances[keys[i]] < distances[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	closest := make([]ClosestKey, len(keys))
+	for i, recordedKey := range keys {
+		closest[i] = ClosestKey{
+			Key:  recordedKey,
+			Diff: diff(string(recordedKey), string(key)),
+		}
+	}
+
+	return closest
+}
+
+// distance is the Levenshtein distance between a and b.
+// The common prefix and suffix don't change the distance and are skipped,
+// the differing parts are compared up to distanceLimit runes
+// and the runes past it are counted as changed.
+func distance(a, b string) int {
+	a, b = trimCommon(a, b)
+	ra, rb := []rune(a), []rune(b)
+
+	rest := 0
+	if len(ra) > distanceLimit || len(rb) > distanceLimit {
+		rest = max(len(ra), len(rb)) - distanceLimit
+		ra, rb = ra[:min(len(ra), distanceLimit)], rb[:min(len(rb), distanceLimit)]
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)] + rest
+}
+
+// trimCommon cuts the common prefix and suffix of a and b on the rune boundaries.
+func trimCommon(a, b string) (string, string) {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	for i > 0 && i < len(a) && !utf8.RuneStart(a[i]) {
+		i--
+	}
+	a, b = a[i:], b[i:]
+
+	j := 0
+	for j < len(a) && j < len(b) && a[len(a)-1-j] == b[len(b)-1-j] {
+		j++
+	}
+	for j > 0 && !utf8.RuneStart(a[len(a)-j]) {
+		j--
+	}
+
+	return a[:len(a)-j], b[:len(b)-j]
+}
+
+// diff shows both keys one under another with a marker
+// at the first position where they differ.
+func diff(recorded, requested string) string {
+	rr, rq := []rune(recorded), []rune(requested)
+
+	pos := 0
+	for pos < len(rr) && pos < len(rq) && rr[pos] == rq[pos] {
+		pos++
+	}
+
+	return fmt.Sprintf("\t\t- %s\n\t\t+ %s\n\t\t  %s^", recorded, requested, strings.Repeat(" ", pos))
+}
@@ -0,0 +1,77 @@
+package cassete
+
+import (
+	"go-vcr/track"
+	"go-vcr/tracklist"
+)
+
+// Exhaustion is the policy of the replay of the key called more times than it was recorded.
+type Exhaustion int
+
+const (
+	// ExhaustStrict fails the call with TrackNotFoundError.
+	ExhaustStrict Exhaustion = iota
+	// ExhaustRepeatLast replays the last recorded track again.
+	ExhaustRepeatLast
+	// ExhaustCycle replays the recorded tracks from the beginning.
+	ExhaustCycle
+	// ExhaustRecord calls the function and appends the new track to the cassete.
+	ExhaustRecord
+)
+
+// WithExhaustion sets the exhaustion policy for all keys of the cassete.
+func WithExhaustion(policy Exhaustion) Option {
+	return func(c *Cassete) {
+		c.exhaustion = policy
+	}
+}
+
+// WithKeyExhaustion sets the exhaustion policy for the key overriding the cassete one.
+func WithKeyExhaustion(key track.Key, policy Exhaustion) Option {
+	return func(c *Cassete) {
+		c.keyExhaustion[key] = policy
+	}
+}
+
+func (c *Cassete) SetExhaustion(policy Exhaustion) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.exhaustion = policy
+}
+
+func (c *Cassete) SetKeyExhaustion(key track.Key, policy Exhaustion) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.keyExhaustion[key] = policy
+}
+
+func (c *Cassete) exhaustionOf(key track.Key) Exhaustion {
+	if policy, ok := c.keyExhaustion[key]; ok {
+		return policy
+	}
+
+	return c.exhaustion
+}
+
+// nextExhausted returns the track to replay for the exhausted key according to its policy.
+func (c *Cassete) nextExhausted(key track.Key, trackList *tracklist.TrackList) *track.Track {
+	switch c.exhaustionOf(key) {
+	case ExhaustRepeatLast:
+		return trackList.Last()
+	case ExhaustCycle:
+		return trackList.NextCycled()
+	}
+
+	return nil
+}
+
+// isRecordOnExhaustion reports whether the exhausted key must be recorded again.
+func (c *Cassete) isRecordOnExhaustion(key track.Key) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	_, ok := c.tracks[key]
+	return ok && c.exhaustionOf(key) == ExhaustRecord
+}
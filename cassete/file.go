@@ -0,0 +1,147 @@
+package cassete
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go-vcr/track"
+)
+
+// FormatVersion is the version of the cassete files.
+// Version 2 keys the tracks by the type-tagged args.
+const FormatVersion = 2
+
+var ErrCasseteNotFound = errors.New("Cassete file not found")
+var ErrCasseteCorrupted = errors.New("Cassete file is corrupted")
+var ErrUnsupportedVersion = errors.New("Cassete file format version is unsupported")
+
+type casseteFile struct {
+	Version int      `json:"version"`
+	Cassete *Cassete `json:"cassete"`
+}
+
+// Load reads the cassete from the file at path
+// with the codec chosen by the file extension, see CodecFor.
+// The compressed files are decompressed while reading, the compressor
+// is chosen by the extension or by the magic bytes the file starts with.
+// The options are applied before reading, so the mode set by them is kept.
+func Load(path string, opts ...Option) (*Cassete, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s: %w", ErrCasseteNotFound, path, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := New(opts...)
+	err = decodeFile(path, f, c)
+	if err != nil {
+		return nil, loadError(path, err)
+	}
+
+	return c, nil
+}
+
+// loadError wraps the error of decoding the file at path,
+// the files of the other format or key version are unsupported,
+// the other errors make the file corrupted.
+func loadError(path string, err error) error {
+	switch {
+	case errors.Is(err, ErrUnsupportedVersion):
+		return fmt.Errorf("%s: %w", path, err)
+	case errors.Is(err, track.ErrUnsupportedKeyVersion):
+		return fmt.Errorf("%w: %s: %w", ErrUnsupportedVersion, path, err)
+	}
+
+	return fmt.Errorf("%w: %s: %w", ErrCasseteCorrupted, path, err)
+}
+
+func decodeFile(path string, f io.Reader, c *Cassete) error {
+	br := bufio.NewReader(f)
+
+	compressor, _ := compressorFor(path)
+	if compressor == nil {
+		compressor = sniffCompressor(br)
+	}
+	if compressor == nil {
+		return CodecFor(path).Decode(br, c)
+	}
+
+	r, err := compressor.Decompress(br)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return CodecFor(path).Decode(r, c)
+}
+
+// Save writes the cassete to the file at path creating the missing directories.
+// The codec and the compressor are chosen by the extension the same way as by Load.
+// The file is written to a temporary file first and renamed then,
+// so the file at path is never left partially written.
+func (c *Cassete) Save(path string) error {
+	codec := CodecFor(path)
+	compressor, _ := compressorFor(path)
+
+	return writeFileAtomic(path, func(w io.Writer) error {
+		if compressor == nil {
+			return codec.Encode(w, c)
+		}
+
+		cw, err := compressor.Compress(w)
+		if err != nil {
+			return err
+		}
+
+		err = codec.Encode(cw, c)
+		if errClose := cw.Close(); err == nil {
+			err = errClose
+		}
+
+		return err
+	})
+}
+
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	buf := bufio.NewWriter(tmp)
+	err = write(buf)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
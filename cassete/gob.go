@@ -0,0 +1,156 @@
+package cassete
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"time"
+
+	"go-vcr/internal/treegob"
+	"go-vcr/track"
+)
+
+// gobCodec encodes the cassete dump to gob with the values as tree nodes,
+// so gob needs no types registered and the values are decoded the same as from YAML.
+type gobCodec struct{}
+
+type casseteForGob struct {
+	Version int
+	ID      uint64
+	Tracks  []trackForGob
+}
+
+type trackForGob struct {
+	Name       string
+	Seq        int
+	Func       string
+	Key        track.Key
+	KeyVersion int
+
+	Args    []treegob.Node
+	Results []treegob.Node
+
+	IsRecorded bool
+	Duration   time.Duration
+	Panic      treegob.Node
+}
+
+func (gobCodec) Encode(w io.Writer, c *Cassete) error {
+	d, err := c.Dump()
+	if err != nil {
+		return err
+	}
+
+	cas := casseteForGob{
+		Version: FormatVersion,
+		ID:      d.ID,
+		Tracks:  make([]trackForGob, len(d.Tracks)),
+	}
+	for i, tr := range d.Tracks {
+		args, err := nodesOf(tr.Args)
+		if err != nil {
+			return err
+		}
+		results, err := nodesOf(tr.Results)
+		if err != nil {
+			return err
+		}
+		p, err := treegob.NodeOf(tr.Panic)
+		if err != nil {
+			return err
+		}
+
+		cas.Tracks[i] = trackForGob{
+			Name:       tr.Name,
+			Seq:        tr.Seq,
+			Func:       tr.Func,
+			Key:        tr.Key,
+			KeyVersion: tr.KeyVersion,
+
+			Args:    args,
+			Results: results,
+
+			IsRecorded: tr.IsRecorded,
+			Duration:   tr.Duration,
+			Panic:      p,
+		}
+	}
+
+	return gob.NewEncoder(w).Encode(cas)
+}
+
+func (gobCodec) Decode(r io.Reader, c *Cassete) error {
+	var cas casseteForGob
+	err := gob.NewDecoder(r).Decode(&cas)
+	if err != nil {
+		return err
+	}
+	if cas.Version != FormatVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedVersion, cas.Version)
+	}
+
+	d := Dump{ID: cas.ID, Tracks: make([]track.Dump, len(cas.Tracks))}
+	for i, tr := range cas.Tracks {
+		args, err := treesOf(tr.Args)
+		if err != nil {
+			return err
+		}
+		results, err := treesOf(tr.Results)
+		if err != nil {
+			return err
+		}
+		p, err := tr.Panic.Tree()
+		if err != nil {
+			return err
+		}
+
+		d.Tracks[i] = track.Dump{
+			Name:       tr.Name,
+			Seq:        tr.Seq,
+			Func:       tr.Func,
+			Key:        tr.Key,
+			KeyVersion: tr.KeyVersion,
+
+			Args:    args,
+			Results: results,
+
+			IsRecorded: tr.IsRecorded,
+			Duration:   tr.Duration,
+			Panic:      p,
+		}
+	}
+
+	return c.Restore(d)
+}
+
+func nodesOf(trees []interface{}) ([]treegob.Node, error) {
+	if trees == nil {
+		return nil, nil
+	}
+
+	nodes := make([]treegob.Node, len(trees))
+	for i := range trees {
+		n, err := treegob.NodeOf(trees[i])
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = n
+	}
+
+	return nodes, nil
+}
+
+// treesOf restores the trees, the empty lists are decoded
+// as the empty slices the same as by YAML.
+func treesOf(nodes []treegob.Node) ([]interface{}, error) {
+	trees := make([]interface{}, len(nodes))
+	for i := range nodes {
+		t, err := nodes[i].Tree()
+		if err != nil {
+			return nil, err
+		}
+		trees[i] = t
+	}
+
+	return trees, nil
+}
@@ -0,0 +1,362 @@
+package cassete
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go-vcr/track"
+	"go-vcr/tracklist"
+)
+
+var ErrJournalClosed = errors.New("Journal is closed")
+var ErrJournalOfOtherCassete = errors.New("Journal holds the tracks of another cassete")
+
+type SyncPolicy int
+
+const (
+	// SyncOnClose leaves flushing the written tracks to the OS until the journal is closed.
+	// The tracks survive the crash of the process, but not the one of the OS.
+	SyncOnClose SyncPolicy = iota
+	// SyncEveryTrack flushes the journal to the disk before the recording call returns.
+	SyncEveryTrack
+	// SyncPeriodically flushes the journal to the disk after the track
+	// if the sync interval passed since the previous flush.
+	SyncPeriodically
+)
+
+// Journal is the append-only log of the recorded tracks,
+// every line of it is a JSON object. The cassete writes the track
+// to the journal as soon as it's recorded, so the tracks recorded
+// before the crash can be loaded by LoadJournal.
+//
+// The journal holding the tracks is appended only by the cassete
+// it was written by, so the cassete recording after the crash
+// is loaded by LoadJournal first.
+type Journal struct {
+	file     *os.File
+	policy   SyncPolicy
+	interval time.Duration
+	lastSync time.Time
+	isEmpty  bool
+	id       uint64
+
+	mutex sync.Mutex
+}
+
+type JournalOption func(*Journal)
+
+func WithSync(policy SyncPolicy) JournalOption {
+	return func(j *Journal) {
+		j.policy = policy
+	}
+}
+
+// WithSyncInterval makes the journal flush to the disk at most once per interval.
+func WithSyncInterval(interval time.Duration) JournalOption {
+	return func(j *Journal) {
+		j.policy = SyncPeriodically
+		j.interval = interval
+	}
+}
+
+// journalLine is the line of the journal. The first line of the journal
+// is the header holding the format version and the cassete ID,
+// the next ones hold the tracks with their keys in the cassete.
+type journalLine struct {
+	Version int          `json:"version,omitempty"`
+	ID      uint64       `json:"id,omitempty"`
+	Key     track.Key    `json:"key,omitempty"`
+	Track   *track.Track `json:"track,omitempty"`
+}
+
+// OpenJournal opens the journal at path creating the missing directories
+// and the file. The tracks are appended to the ones already in the journal,
+// the partially written last line left by the crash is truncated first.
+func OpenJournal(path string, opts ...JournalOption) (*Journal, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := truncatePartialLine(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	id, err := readJournalID(file, size)
+	if errors.Is(err, ErrUnsupportedVersion) {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s: %w", ErrCasseteCorrupted, path, err)
+	}
+
+	j := &Journal{
+		file:     file,
+		lastSync: time.Now(),
+		isEmpty:  size == 0,
+		id:       id,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	return j, nil
+}
+
+// readJournalID reads the ID of the cassete from the header of the journal.
+func readJournalID(file *os.File, size int64) (uint64, error) {
+	if size == 0 {
+		return 0, nil
+	}
+
+	data, err := bufio.NewReader(io.NewSectionReader(file, 0, size)).ReadBytes('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	var header journalLine
+	err = json.Unmarshal(data, &header)
+	if err != nil {
+		return 0, err
+	}
+	if header.Version != FormatVersion {
+		return 0, fmt.Errorf("%w: version %d", ErrUnsupportedVersion, header.Version)
+	}
+
+	return header.ID, nil
+}
+
+// truncatePartialLine truncates the file after its last complete line
+// and returns the size of the file, so the lines appended later
+// don't continue the partially written one.
+func truncatePartialLine(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	size := info.Size()
+	end := size
+	buf := make([]byte, 4096)
+	for end > 0 {
+		n := int64(len(buf))
+		if n > end {
+			n = end
+		}
+
+		_, err := file.ReadAt(buf[:n], end-n)
+		if err != nil {
+			return 0, err
+		}
+
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = end - n + int64(i) + 1
+			break
+		}
+		end -= n
+	}
+
+	if end == size {
+		return size, nil
+	}
+
+	return end, file.Truncate(end)
+}
+
+// Close flushes the journal to the disk and closes it.
+func (j *Journal) Close() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		return ErrJournalClosed
+	}
+
+	err := j.file.Sync()
+	if errClose := j.file.Close(); err == nil {
+		err = errClose
+	}
+	j.file = nil
+
+	return err
+}
+
+// WithJournal makes the cassete write the recorded tracks to the journal.
+func WithJournal(j *Journal) Option {
+	return func(c *Cassete) {
+		c.journal = j
+	}
+}
+
+func (c *Cassete) SetJournal(j *Journal) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.journal = j
+}
+
+// syncJournal flushes the journal after the cassete is unlocked,
+// so the replays aren't blocked while the disk is flushed.
+func (c *Cassete) syncJournal() error {
+	c.mutex.RLock()
+	j := c.journal
+	c.mutex.RUnlock()
+
+	if j == nil {
+		return nil
+	}
+
+	return j.sync()
+}
+
+// writeTrack appends the track to the journal
+// preceded by the header if the journal is empty.
+func (j *Journal) writeTrack(c *Cassete, key track.Key, tr *track.Track) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		return ErrJournalClosed
+	}
+	if !j.isEmpty && j.id != c.id {
+		return fmt.Errorf("%w: journal cassete ID %d, cassete ID %d", ErrJournalOfOtherCassete, j.id, c.id)
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	if j.isEmpty {
+		err := encoder.Encode(journalLine{Version: FormatVersion, ID: c.id})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := encoder.Encode(journalLine{Key: key, Track: tr})
+	if err != nil {
+		return err
+	}
+
+	// The line is written by the single call, so the crash
+	// can leave only the last line of the journal partially written.
+	_, err = j.file.Write(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	j.isEmpty = false
+	j.id = c.id
+
+	return nil
+}
+
+// sync flushes the journal to the disk if the sync policy requires it.
+// The closed journal is already flushed by Close.
+func (j *Journal) sync() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+
+	switch j.policy {
+	case SyncEveryTrack:
+	case SyncPeriodically:
+		if time.Since(j.lastSync) < j.interval {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	j.lastSync = time.Now()
+
+	return j.file.Sync()
+}
+
+// LoadJournal rebuilds the cassete from the journal at path.
+// The partially written last line left by the crash is skipped,
+// the other broken lines make the journal corrupted.
+// The options are applied before reading, so the mode set by them is kept.
+func LoadJournal(path string, opts ...Option) (*Cassete, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s: %w", ErrCasseteNotFound, path, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := New(opts...)
+	err = c.readJournal(bufio.NewReader(f))
+	if err != nil {
+		return nil, loadError(path, err)
+	}
+
+	return c, nil
+}
+
+func (c *Cassete) readJournal(r *bufio.Reader) error {
+	id := c.id
+	tracks := make(TrackMap)
+
+	for lineNum := 1; ; lineNum++ {
+		data, errRead := r.ReadBytes('\n')
+		if errRead != nil && errRead != io.EOF {
+			return errRead
+		}
+		isLast := errRead == io.EOF
+		if isLast && len(bytes.TrimSpace(data)) == 0 {
+			break
+		}
+
+		line := journalLine{Track: track.New()}
+		err := json.Unmarshal(data, &line)
+		if err != nil && isLast && !errors.Is(err, track.ErrUnsupportedKeyVersion) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		switch {
+		case line.Version != 0 && line.Version != FormatVersion:
+			return fmt.Errorf("%w: version %d", ErrUnsupportedVersion, line.Version)
+		case line.Version != 0:
+			id = line.ID
+		case line.Key == "":
+			return fmt.Errorf("line %d: no track key", lineNum)
+		default:
+			if _, ok := tracks[line.Key]; !ok {
+				tracks[line.Key] = tracklist.New()
+			}
+			tracks[line.Key].Append(line.Track)
+		}
+
+		if isLast {
+			break
+		}
+	}
+
+	c.restore(id, tracks)
+
+	return nil
+}
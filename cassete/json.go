@@ -0,0 +1,26 @@
+package cassete
+
+import (
+	"encoding/json"
+)
+
+type casseteForJSON struct {
+	ID     uint64   `json:"id"`
+	Tracks TrackMap `json:"tracks"`
+}
+
+func (c *Cassete) MarshalJSON() ([]byte, error) {
+	return json.Marshal(casseteForJSON{ID: c.id, Tracks: c.tracks})
+}
+
+func (c *Cassete) UnmarshalJSON(data []byte) error {
+	cas := new(casseteForJSON)
+	err := json.Unmarshal(data, cas)
+	if err != nil {
+		return err
+	}
+
+	c.restore(cas.ID, cas.Tracks)
+
+	return nil
+}
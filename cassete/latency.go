@@ -0,0 +1,84 @@
+package cassete
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Latency makes the replayed calls take as long as the recorded ones.
+type Latency struct {
+	// Multiplier scales the recorded durations, 1 is used if zero.
+	Multiplier float64
+	// Jitter is the max deviation of the delay as a fraction of it, e.g. 0.1 for ±10%.
+	Jitter float64
+	// Max caps the delay, there is no cap if zero.
+	Max time.Duration
+	// Seed makes the jitter reproducible.
+	Seed int64
+}
+
+type latencySimulator struct {
+	latency Latency
+
+	rand  *rand.Rand
+	mutex sync.Mutex
+}
+
+// WithLatency makes the cassete delay the playback by the recorded duration of the track.
+func WithLatency(latency Latency) Option {
+	return func(c *Cassete) {
+		c.latency = newLatencySimulator(latency)
+	}
+}
+
+func newLatencySimulator(latency Latency) *latencySimulator {
+	if latency.Multiplier == 0 {
+		latency.Multiplier = 1
+	}
+
+	return &latencySimulator{
+		latency: latency,
+		rand:    rand.New(rand.NewSource(latency.Seed)),
+	}
+}
+
+func (l *latencySimulator) delay(duration time.Duration) time.Duration {
+	delay := float64(duration) * l.latency.Multiplier
+
+	if l.latency.Jitter != 0 {
+		l.mutex.Lock()
+		deviation := l.rand.Float64()*2 - 1
+		l.mutex.Unlock()
+
+		delay += delay * l.latency.Jitter * deviation
+	}
+
+	if l.latency.Max != 0 && delay > float64(l.latency.Max) {
+		return l.latency.Max
+	}
+	if delay < 0 {
+		return 0
+	}
+
+	return time.Duration(delay)
+}
+
+// sleep waits for the delay of the recorded duration
+// or returns the context error if it's done earlier.
+func (l *latencySimulator) sleep(ctx context.Context, duration time.Duration) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timer := time.NewTimer(l.delay(duration))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
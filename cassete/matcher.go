@@ -0,0 +1,268 @@
+package cassete
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"go-vcr/track"
+	"go-vcr/tracklist"
+)
+
+// Matcher decides whether the track recorded with the args recorded
+// can be played back for the call with the args actual.
+// Contexts are excluded from both args.
+type Matcher func(recorded, actual []interface{}) bool
+
+// Rule changes the way the values at the path are compared by MatchArgs.
+// The path is the arg index followed by the map keys, struct field names
+// or slice indexes separated by dots, e.g. "0.User.Name".
+// Field names are compared ignoring case, "*" matches any path element.
+type Rule struct {
+	path  []string
+	match func(recorded, actual interface{}) bool
+}
+
+func newRule(path string, match func(recorded, actual interface{}) bool) Rule {
+	return Rule{
+		path:  strings.Split(path, "."),
+		match: match,
+	}
+}
+
+func IgnoreArg(n int) Rule {
+	return IgnoreField(strconv.Itoa(n))
+}
+
+func IgnoreField(path string) Rule {
+	return newRule(path, func(recorded, actual interface{}) bool {
+		return true
+	})
+}
+
+// MatchRegexp matches the actual value at the path by the regular expression
+// instead of comparing it with the recorded one.
+func MatchRegexp(path string, re *regexp.Regexp) Rule {
+	return newRule(path, func(recorded, actual interface{}) bool {
+		str, ok := actual.(string)
+		return ok && re.MatchString(str)
+	})
+}
+
+func NumericTolerance(path string, delta float64) Rule {
+	return newRule(path, func(recorded, actual interface{}) bool {
+		r, okRecorded := toFloat(recorded)
+		a, okActual := toFloat(actual)
+		return okRecorded && okActual && r-a <= delta && a-r <= delta
+	})
+}
+
+func TimeTolerance(path string, delta time.Duration) Rule {
+	return newRule(path, func(recorded, actual interface{}) bool {
+		r, okRecorded := toTime(recorded)
+		a, okActual := toTime(actual)
+		return okRecorded && okActual && r.Sub(a) <= delta && a.Sub(r) <= delta
+	})
+}
+
+// MatchArgs returns the matcher comparing the args deeply
+// with the rules applied to the values at their paths.
+func MatchArgs(rules ...Rule) Matcher {
+	return func(recorded, actual []interface{}) bool {
+		if len(recorded) != len(actual) {
+			return false
+		}
+
+		for i := range recorded {
+			path := []string{strconv.Itoa(i)}
+			if !matchValue(rules, path, normalize(recorded[i]), normalize(actual[i])) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// WithMatcher sets the matcher for the tracks of the function with the name.
+// The matcher is used when no track is recorded with the exact key of the call.
+func WithMatcher(name string, matcher Matcher) Option {
+	return func(c *Cassete) {
+		c.matchers[name] = matcher
+	}
+}
+
+func (c *Cassete) SetMatcher(name string, matcher Matcher) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.matchers[name] = matcher
+}
+
+// nextTrack returns the next track recorded with the key of tr,
+// the next track accepted by the matcher of the function of tr,
+// or the track chosen by the exhaustion policy of the key.
+func (c *Cassete) nextTrack(tr *track.Track) *track.Track {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := tr.Key()
+	trackList, ok := c.tracks[key]
+	if ok {
+		if trNext := trackList.Next(); trNext != nil {
+			return trNext
+		}
+	}
+
+	if trNext := c.nextMatched(tr); trNext != nil {
+		return trNext
+	}
+
+	if ok {
+		return c.nextExhausted(key, trackList)
+	}
+
+	return nil
+}
+
+func (c *Cassete) nextMatched(tr *track.Track) *track.Track {
+	matcher, ok := c.matchers[tr.Name()]
+	if !ok {
+		return nil
+	}
+
+	keys := make([]track.Key, 0, len(c.tracks))
+	for key := range c.tracks {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	for _, key := range keys {
+		trackList := c.tracks[key]
+		if isMatched(trackList, tr, matcher) {
+			return trackList.Next()
+		}
+	}
+
+	return nil
+}
+
+func isMatched(trackList *tracklist.TrackList, tr *track.Track, matcher Matcher) bool {
+	trNext := trackList.Peek()
+	if trNext == nil || trNext.Name() != tr.Name() {
+		return false
+	}
+
+	return matcher(trNext.KeyArgs(), tr.KeyArgs())
+}
+
+func matchValue(rules []Rule, path []string, recorded, actual interface{}) bool {
+	for _, rule := range rules {
+		if isPathMatched(rule.path, path) {
+			return rule.match(recorded, actual)
+		}
+	}
+
+	switch r := recorded.(type) {
+	case map[interface{}]interface{}:
+		a, ok := actual.(map[interface{}]interface{})
+		if !ok || len(a) != len(r) {
+			return false
+		}
+
+		for key, value := range r {
+			valueActual, ok := a[key]
+			if !ok || !matchValue(rules, append(path, toString(key)), value, valueActual) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(r) {
+			return false
+		}
+
+		for i := range r {
+			if !matchValue(rules, append(path, strconv.Itoa(i)), r[i], a[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(recorded, actual)
+}
+
+func isPathMatched(rulePath, path []string) bool {
+	if len(rulePath) != len(path) {
+		return false
+	}
+
+	for i := range path {
+		if rulePath[i] != "*" && !strings.EqualFold(rulePath[i], path[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalize brings the live and the restored values to the same form
+// by encoding them to yaml and decoding back.
+func normalize(value interface{}) interface{} {
+	dump, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	err = yaml.Unmarshal(dump, &normalized)
+	if err != nil {
+		return value
+	}
+
+	return normalized
+}
+
+func toString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+
+	dump, _ := yaml.Marshal(value)
+	return strings.TrimSpace(string(dump))
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	}
+
+	return time.Time{}, false
+}
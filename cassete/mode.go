@@ -0,0 +1,55 @@
+package cassete
+
+type Mode int
+
+const (
+	ModeRecord Mode = iota
+	ModeReplay
+	ModeReplayOrRecord
+	ModePassthrough
+	ModeDisabled
+	// ModeRecordStale replays the tracks and records again the stale ones,
+	// which function signature changed since recording.
+	ModeRecordStale
+)
+
+var modeNames = map[Mode]string{
+	ModeRecord:         "Record",
+	ModeReplay:         "Replay",
+	ModeReplayOrRecord: "ReplayOrRecord",
+	ModePassthrough:    "Passthrough",
+	ModeDisabled:       "Disabled",
+	ModeRecordStale:    "RecordStale",
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+
+	return "Unknown"
+}
+
+type Option func(*Cassete)
+
+func WithMode(mode Mode) Option {
+	return func(c *Cassete) {
+		c.mode = mode
+		c.isModeSet = true
+	}
+}
+
+func (c *Cassete) Mode() Mode {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.mode
+}
+
+func (c *Cassete) SetMode(mode Mode) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.mode = mode
+	c.isModeSet = true
+}
@@ -0,0 +1,177 @@
+package cassete
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go-vcr/track"
+)
+
+var ErrOrderViolation = errors.New("Track is played out of the recorded order")
+
+// Order is the policy of checking that the calls are replayed
+// in the same order across all keys as they were recorded.
+type Order int
+
+const (
+	// OrderIgnore doesn't check the order.
+	OrderIgnore Order = iota
+	// OrderStrict fails the call played out of order with OrderError.
+	OrderStrict
+	// OrderLenient plays the call and reports the violation by OrderViolations.
+	OrderLenient
+)
+
+type OrderViolation struct {
+	Key         track.Key
+	Seq         int
+	ExpectedKey track.Key
+	ExpectedSeq int
+}
+
+func (v OrderViolation) String() string {
+	return fmt.Sprintf("got #%d %s, expected #%d %s", v.Seq, v.Key, v.ExpectedSeq, v.ExpectedKey)
+}
+
+type OrderError struct {
+	OrderViolation
+}
+
+func (e *OrderError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrOrderViolation, e.OrderViolation)
+}
+
+func (e *OrderError) Is(target error) bool {
+	return target == ErrOrderViolation
+}
+
+func WithOrder(order Order) Option {
+	return func(c *Cassete) {
+		c.order.policy = order
+	}
+}
+
+func (c *Cassete) SetOrder(order Order) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.order.policy = order
+}
+
+// OrderViolations returns the violations of the recorded order found in the lenient mode.
+func (c *Cassete) OrderViolations() []OrderViolation {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return append([]OrderViolation(nil), c.order.violations...)
+}
+
+// orderTracker keeps the sequence numbers of the recorded tracks in the order
+// of recording and the position of the first track which wasn't played yet.
+type orderTracker struct {
+	policy     Order
+	lastSeq    int
+	seqs       []int
+	keys       map[int]track.Key
+	played     map[int]bool
+	cursor     int
+	violations []OrderViolation
+}
+
+func (o *orderTracker) add(key track.Key, seq int) {
+	if seq > o.lastSeq {
+		o.lastSeq = seq
+	}
+	if o.keys == nil {
+		o.keys = make(map[int]track.Key)
+		o.played = make(map[int]bool)
+	}
+
+	o.seqs = append(o.seqs, seq)
+	o.keys[seq] = key
+}
+
+// reset rebuilds the tracker for the tracks of the restored cassete.
+func (o *orderTracker) reset(tracks TrackMap) {
+	*o = orderTracker{policy: o.policy}
+
+	for key, trackList := range tracks {
+		for _, tr := range trackList.Tracks() {
+			if tr.Seq() != 0 {
+				o.add(key, tr.Seq())
+			}
+		}
+	}
+
+	sort.Ints(o.seqs)
+}
+
+// remove forgets the sequence number of the dropped track,
+// so the tracks after it aren't waiting for it.
+func (o *orderTracker) remove(seq int) {
+	i := sort.SearchInts(o.seqs, seq)
+	if i == len(o.seqs) || o.seqs[i] != seq {
+		return
+	}
+
+	o.seqs = append(o.seqs[:i], o.seqs[i+1:]...)
+	delete(o.keys, seq)
+	delete(o.played, seq)
+	if i < o.cursor {
+		o.cursor--
+	}
+	for o.cursor < len(o.seqs) && o.played[o.seqs[o.cursor]] {
+		o.cursor++
+	}
+}
+
+func (o *orderTracker) markPlayed(seq int) {
+	o.played[seq] = true
+	for o.cursor < len(o.seqs) && o.played[o.seqs[o.cursor]] {
+		o.cursor++
+	}
+}
+
+// check marks the track as played and returns the violation if the track
+// isn't the first one not played yet. The replays of played tracks are ignored.
+func (o *orderTracker) check(seq int) *OrderViolation {
+	if seq == 0 || o.played[seq] || o.cursor == len(o.seqs) {
+		return nil
+	}
+
+	var violation *OrderViolation
+	if expected := o.seqs[o.cursor]; expected != seq {
+		violation = &OrderViolation{
+			Key:         o.keys[seq],
+			Seq:         seq,
+			ExpectedKey: o.keys[expected],
+			ExpectedSeq: expected,
+		}
+	}
+
+	o.markPlayed(seq)
+
+	return violation
+}
+
+func (c *Cassete) checkOrder(trRecorded *track.Track) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.order.policy == OrderIgnore {
+		return nil
+	}
+
+	violation := c.order.check(trRecorded.Seq())
+	if violation == nil {
+		return nil
+	}
+
+	if c.order.policy == OrderLenient {
+		c.order.violations = append(c.order.violations, *violation)
+		return nil
+	}
+
+	return &OrderError{OrderViolation: *violation}
+}
@@ -0,0 +1,81 @@
+package cassete
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-vcr/track"
+)
+
+type TrackStat struct {
+	Key      track.Key
+	Recorded int
+	Played   int
+}
+
+func (s TrackStat) Remaining() int {
+	return s.Recorded - s.Played
+}
+
+// Stats returns the numbers of the recorded and played tracks per key sorted by key.
+func (c *Cassete) Stats() []TrackStat {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	stats := make([]TrackStat, 0, len(c.tracks))
+	for key, trackList := range c.tracks {
+		stats = append(stats, TrackStat{
+			Key:      key,
+			Recorded: trackList.Length(),
+			Played:   trackList.Played(),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Key < stats[j].Key })
+
+	return stats
+}
+
+// Unplayed returns the stats of the keys having tracks which weren't played.
+func (c *Cassete) Unplayed() []TrackStat {
+	unplayed := make([]TrackStat, 0)
+	for _, stat := range c.Stats() {
+		if stat.Remaining() > 0 {
+			unplayed = append(unplayed, stat)
+		}
+	}
+
+	return unplayed
+}
+
+// TestingT is the part of testing.TB used to report the failures.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertAllPlayed fails the test if some recorded tracks weren't played,
+// so the test notices the calls the code stopped making.
+// The cassete recording the calls in ModeRecord or ModePassthrough
+// doesn't replay them, so the check always passes for it.
+func (c *Cassete) AssertAllPlayed(t TestingT) bool {
+	t.Helper()
+
+	if mode := c.Mode(); mode == ModeRecord || mode == ModePassthrough {
+		return true
+	}
+
+	unplayed := c.Unplayed()
+	if len(unplayed) == 0 {
+		return true
+	}
+
+	var b strings.Builder
+	for _, stat := range unplayed {
+		fmt.Fprintf(&b, "\n\t%s: %d of %d tracks unplayed", stat.Key, stat.Remaining(), stat.Recorded)
+	}
+	t.Errorf("cassete: not all tracks were played:%s", b.String())
+
+	return false
+}
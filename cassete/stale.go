@@ -0,0 +1,92 @@
+package cassete
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go-vcr/track"
+)
+
+var ErrStaleTrack = errors.New("Track is recorded with another function signature")
+
+// StaleTrackError describes the change of the function signature
+// since the tracks of the function were recorded.
+type StaleTrackError struct {
+	Name           string
+	RecordedFnType string
+	FnType         string
+	Keys           []track.Key
+}
+
+func (e *StaleTrackError) Error() string {
+	return fmt.Sprintf("%s: %s is recorded as %s, but called as %s", ErrStaleTrack, e.Name, e.RecordedFnType, e.FnType)
+}
+
+func (e *StaleTrackError) Is(target error) bool {
+	return target == ErrStaleTrack
+}
+
+// CheckStale returns StaleTrackError if the tracks of some of the functions fns
+// are recorded with another signature, so the cassete can be checked right after load.
+func (c *Cassete) CheckStale(fns ...interface{}) error {
+	for _, fn := range fns {
+		err := c.staleError(track.New().Call(fn))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// staleError returns StaleTrackError if the function of tr is recorded with another signature.
+// The tracks recorded without the signature aren't considered stale.
+func (c *Cassete) staleError(tr *track.Track) *StaleTrackError {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var err *StaleTrackError
+	for key, trackList := range c.tracks {
+		trRecorded := trackList.Last()
+		if trRecorded == nil || trRecorded.Name() != tr.Name() {
+			continue
+		}
+		if trRecorded.FnType() == "" || trRecorded.FnType() == tr.FnType() {
+			continue
+		}
+
+		if err == nil {
+			err = &StaleTrackError{
+				Name:           tr.Name(),
+				RecordedFnType: trRecorded.FnType(),
+				FnType:         tr.FnType(),
+			}
+		}
+		err.Keys = append(err.Keys, key)
+	}
+
+	if err != nil {
+		sort.Slice(err.Keys, func(i, j int) bool { return err.Keys[i] < err.Keys[j] })
+	}
+
+	return err
+}
+
+// dropStale removes the stale tracks, so they are recorded again.
+func (c *Cassete) dropStale(err *StaleTrackError) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, key := range err.Keys {
+		trackList, ok := c.tracks[key]
+		if !ok {
+			continue
+		}
+
+		for _, tr := range trackList.Tracks() {
+			c.order.remove(tr.Seq())
+		}
+		delete(c.tracks, key)
+	}
+}
@@ -1,5 +1,12 @@
 package cassete
 
+import (
+	"sort"
+
+	"go-vcr/track"
+	"go-vcr/tracklist"
+)
+
 type casseteForYAML struct {
 	ID     uint64
 	Tracks TrackMap
@@ -21,9 +28,33 @@ func (c *Cassete) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
-	c.id = cas.ID
-	c.tracks = cas.Tracks
-	c.isRecorded = true
+	c.restore(cas.ID, cas.Tracks)
 
 	return nil
 }
+
+// restore sets the decoded tracks, the cassete replays them
+// unless the mode was set explicitly. The tracks are keyed by their own keys,
+// so the map keys of the dump aren't trusted.
+func (c *Cassete) restore(id uint64, tracks TrackMap) {
+	keys := make([]track.Key, 0, len(tracks))
+	for key := range tracks {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	c.id = id
+	c.tracks = make(TrackMap, len(tracks))
+	for _, key := range keys {
+		for _, tr := range tracks[key].Tracks() {
+			if _, ok := c.tracks[tr.Key()]; !ok {
+				c.tracks[tr.Key()] = tracklist.New()
+			}
+			c.tracks[tr.Key()].Append(tr)
+		}
+	}
+	c.order.reset(c.tracks)
+	if !c.isModeSet {
+		c.mode = ModeReplay
+	}
+}
@@ -0,0 +1,385 @@
+// Package binrec encodes the length-prefixed binary records.
+//
+// The strings written with Ref are kept in a table shared by all the records:
+// the first occurrence of a string is written in full and the later ones
+// refer to its index in the table.
+//
+// The values are the trees YAML decodes to, see internal/tree,
+// so they are decoded the same as from YAML.
+package binrec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var ErrCorrupted = errors.New("Binary record is corrupted")
+
+// The max length of a record, it protects from allocating
+// the huge buffers when the length is corrupted.
+const maxRecordLength = 1 << 30
+
+const (
+	tagNil byte = iota
+	tagString
+	tagStringRef
+	tagInt
+	tagUint
+	tagFloat
+	tagFalse
+	tagTrue
+	tagList
+	tagMap
+)
+
+type Encoder struct {
+	w       io.Writer
+	rec     []byte
+	strings map[string]uint64
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:       w,
+		strings: make(map[string]uint64),
+	}
+}
+
+// Flush writes the record built by the previous calls.
+func (e *Encoder) Flush() error {
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], uint64(len(e.rec)))
+
+	_, err := e.w.Write(prefix[:n])
+	if err == nil {
+		_, err = e.w.Write(e.rec)
+	}
+	e.rec = e.rec[:0]
+
+	return err
+}
+
+func (e *Encoder) Uvarint(u uint64) {
+	e.rec = binary.AppendUvarint(e.rec, u)
+}
+
+func (e *Encoder) Varint(i int64) {
+	e.rec = binary.AppendVarint(e.rec, i)
+}
+
+func (e *Encoder) Bool(b bool) {
+	if b {
+		e.rec = append(e.rec, 1)
+		return
+	}
+	e.rec = append(e.rec, 0)
+}
+
+func (e *Encoder) String(s string) {
+	e.Uvarint(uint64(len(s)))
+	e.rec = append(e.rec, s...)
+}
+
+// Ref writes the string through the string table.
+func (e *Encoder) Ref(s string) {
+	if i, ok := e.strings[s]; ok {
+		e.Uvarint(i + 1)
+		return
+	}
+
+	e.strings[s] = uint64(len(e.strings))
+	e.Uvarint(0)
+	e.String(s)
+}
+
+// Value writes the tree.
+func (e *Encoder) Value(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		e.rec = append(e.rec, tagNil)
+	case string:
+		e.rec = append(e.rec, tagString)
+		e.String(v)
+	case int:
+		e.rec = append(e.rec, tagInt)
+		e.Varint(int64(v))
+	case int64:
+		e.rec = append(e.rec, tagInt)
+		e.Varint(v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			e.rec = append(e.rec, tagInt)
+			e.Varint(int64(v))
+			return nil
+		}
+		e.rec = append(e.rec, tagUint)
+		e.Uvarint(v)
+	case bool:
+		if v {
+			e.rec = append(e.rec, tagTrue)
+		} else {
+			e.rec = append(e.rec, tagFalse)
+		}
+	case []interface{}:
+		e.rec = append(e.rec, tagList)
+		e.Uvarint(uint64(len(v)))
+		for _, item := range v {
+			err := e.Value(item)
+			if err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		e.rec = append(e.rec, tagMap)
+		e.Uvarint(uint64(len(v)))
+		for key, value := range v {
+			err := e.key(key)
+			if err != nil {
+				return err
+			}
+			err = e.Value(value)
+			if err != nil {
+				return err
+			}
+		}
+	case float64:
+		e.rec = append(e.rec, tagFloat)
+		e.rec = binary.LittleEndian.AppendUint64(e.rec, math.Float64bits(v))
+	default:
+		return fmt.Errorf("binrec: %T isn't a tree", v)
+	}
+
+	return nil
+}
+
+// key writes the string map keys through the string table,
+// as the field names are repeated in every record.
+func (e *Encoder) key(key interface{}) error {
+	if s, ok := key.(string); ok {
+		e.rec = append(e.rec, tagStringRef)
+		e.Ref(s)
+		return nil
+	}
+
+	return e.Value(key)
+}
+
+// Decoder reads the records written by Encoder.
+// The first error is kept and returned by Err,
+// the reads after it return the zero values.
+type Decoder struct {
+	r       *bufio.Reader
+	buf     []byte
+	rec     []byte
+	strings []string
+	err     error
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	return &Decoder{r: br}
+}
+
+// Next reads the next record. It returns false at the end of the stream
+// or on the error, which is returned by Err then.
+func (d *Decoder) Next() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.rec) != 0 {
+		d.fail(fmt.Errorf("%w: %d bytes left unread", ErrCorrupted, len(d.rec)))
+		return false
+	}
+
+	length, err := binary.ReadUvarint(d.r)
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		d.fail(err)
+		return false
+	}
+	if length > maxRecordLength {
+		d.fail(fmt.Errorf("%w: record length %d", ErrCorrupted, length))
+		return false
+	}
+
+	if uint64(cap(d.buf)) < length {
+		d.buf = make([]byte, length)
+	}
+	d.rec = d.buf[:length]
+	_, err = io.ReadFull(d.r, d.rec)
+	if err != nil {
+		d.fail(err)
+		return false
+	}
+
+	return true
+}
+
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func (d *Decoder) fail(err error) {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if !errors.Is(err, ErrCorrupted) {
+		err = fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	if d.err == nil {
+		d.err = err
+	}
+	d.rec = nil
+}
+
+func (d *Decoder) Uvarint() uint64 {
+	u, n := binary.Uvarint(d.rec)
+	if n <= 0 {
+		d.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+	d.rec = d.rec[n:]
+
+	return u
+}
+
+func (d *Decoder) Varint() int64 {
+	i, n := binary.Varint(d.rec)
+	if n <= 0 {
+		d.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+	d.rec = d.rec[n:]
+
+	return i
+}
+
+func (d *Decoder) Bool() bool {
+	return d.byte() == 1
+}
+
+func (d *Decoder) String() string {
+	length := d.Uvarint()
+	if length > uint64(len(d.rec)) {
+		d.fail(io.ErrUnexpectedEOF)
+		return ""
+	}
+
+	s := string(d.rec[:length])
+	d.rec = d.rec[length:]
+
+	return s
+}
+
+func (d *Decoder) Ref() string {
+	i := d.Uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if i == 0 {
+		s := d.String()
+		d.strings = append(d.strings, s)
+		return s
+	}
+	if i > uint64(len(d.strings)) {
+		d.fail(fmt.Errorf("%w: unknown string %d", ErrCorrupted, i-1))
+		return ""
+	}
+
+	return d.strings[i-1]
+}
+
+func (d *Decoder) Value() interface{} {
+	switch tag := d.byte(); tag {
+	case tagNil:
+		return nil
+	case tagString:
+		return d.String()
+	case tagStringRef:
+		return d.Ref()
+	case tagInt:
+		i := d.Varint()
+		if i >= math.MinInt && i <= math.MaxInt {
+			return int(i)
+		}
+		return i
+	case tagUint:
+		return d.Uvarint()
+	case tagFloat:
+		if len(d.rec) < 8 {
+			d.fail(io.ErrUnexpectedEOF)
+			return nil
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(d.rec))
+		d.rec = d.rec[8:]
+		return f
+	case tagFalse:
+		return false
+	case tagTrue:
+		return true
+	case tagList:
+		length := d.Length()
+		s := make([]interface{}, length)
+		for i := range s {
+			s[i] = d.Value()
+		}
+		return s
+	case tagMap:
+		length := d.Length()
+		m := make(map[interface{}]interface{}, length)
+		for i := 0; i < length; i++ {
+			key := d.Value()
+			switch key.(type) {
+			case []interface{}, map[interface{}]interface{}:
+				d.fail(fmt.Errorf("%w: map key isn't comparable", ErrCorrupted))
+			}
+			if d.err != nil {
+				return nil
+			}
+			m[key] = d.Value()
+		}
+		return m
+	default:
+		if d.err == nil {
+			d.fail(fmt.Errorf("%w: unknown value tag %d", ErrCorrupted, tag))
+		}
+		return nil
+	}
+}
+
+// length reads the number of the items, which can't exceed
+// the number of the bytes left as every item takes a byte at least.
+// Length reads the count of the items following it in the record.
+// Every item takes a byte at least, so the count exceeding the rest
+// of the record fails the decoder and 0 is returned.
+func (d *Decoder) Length() int {
+	length := d.Uvarint()
+	if length > uint64(len(d.rec)) {
+		d.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+
+	return int(length)
+}
+
+func (d *Decoder) byte() byte {
+	if len(d.rec) == 0 {
+		d.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+
+	b := d.rec[0]
+	d.rec = d.rec[1:]
+
+	return b
+}
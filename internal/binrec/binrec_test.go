@@ -0,0 +1,79 @@
+package binrec_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-vcr/internal/binrec"
+)
+
+func TestEncoderAndDecoder(t *testing.T) {
+	values := []interface{}{
+		nil,
+		"Alice",
+		-5,
+		uint64(math.MaxUint64),
+		true,
+		[]interface{}{1, "two"},
+		map[interface{}]interface{}{"key": 1.5, 2: false},
+		map[interface{}]interface{}{"name": "Alice", "map": map[interface{}]interface{}{1: "one"}},
+	}
+
+	buf := new(bytes.Buffer)
+	e := binrec.NewEncoder(buf)
+	for i := 0; i < 2; i++ {
+		e.Ref("repeated")
+		e.Varint(-1)
+		for _, v := range values {
+			assert.Nil(t, e.Value(v))
+		}
+		assert.Nil(t, e.Flush())
+	}
+
+	d := binrec.NewDecoder(buf)
+	for i := 0; i < 2; i++ {
+		assert.True(t, d.Next())
+		assert.Equal(t, "repeated", d.Ref())
+		assert.Equal(t, int64(-1), d.Varint())
+		for _, v := range values {
+			assert.Equal(t, v, d.Value())
+		}
+	}
+	assert.False(t, d.Next())
+	assert.Nil(t, d.Err())
+}
+
+func TestEncoderNotTree(t *testing.T) {
+	e := binrec.NewEncoder(new(bytes.Buffer))
+	assert.NotNil(t, e.Value(struct{}{}))
+}
+
+func TestDecoderCorrupted(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := binrec.NewEncoder(buf)
+	e.String("Hello world")
+	e.Flush()
+
+	t.Run("Truncated record", func(t *testing.T) {
+		d := binrec.NewDecoder(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+		assert.False(t, d.Next())
+		assert.True(t, errors.Is(d.Err(), binrec.ErrCorrupted))
+	})
+	t.Run("Record isn't read till the end", func(t *testing.T) {
+		d := binrec.NewDecoder(bytes.NewReader(append(buf.Bytes(), buf.Bytes()...)))
+		assert.True(t, d.Next())
+		assert.False(t, d.Next())
+		assert.True(t, errors.Is(d.Err(), binrec.ErrCorrupted))
+	})
+	t.Run("Read past the record", func(t *testing.T) {
+		d := binrec.NewDecoder(bytes.NewReader(buf.Bytes()))
+		assert.True(t, d.Next())
+		_ = d.String()
+		d.Value()
+		assert.True(t, errors.Is(d.Err(), binrec.ErrCorrupted))
+	})
+}
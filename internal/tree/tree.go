@@ -0,0 +1,79 @@
+// Package tree converts the values to the trees YAML decodes them to.
+// A tree consists of nil, string, int, int64, uint64, float64, bool,
+// []interface{} and map[interface{}]interface{} values, so the tree
+// is encoded by any codec the same way it's encoded by YAML.
+package tree
+
+import (
+	"math"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Of converts the value to the tree. The values of the types a tree
+// consists of are converted directly, others are converted by YAML.
+func Of(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, string, bool:
+		return v, nil
+	case int:
+		return v, nil
+	case int64:
+		if v >= math.MinInt && v <= math.MaxInt {
+			return int(v), nil
+		}
+		return v, nil
+	case uint64:
+		if v <= math.MaxInt {
+			return int(v), nil
+		}
+		return v, nil
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i := range v {
+			item, err := Of(v[i])
+			if err != nil {
+				return nil, err
+			}
+			s[i] = item
+		}
+		return s, nil
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for key, value := range v {
+			keyTree, err := Of(key)
+			if err != nil {
+				return nil, err
+			}
+			valueTree, err := Of(value)
+			if err != nil {
+				return nil, err
+			}
+			m[keyTree] = valueTree
+		}
+		return m, nil
+	}
+
+	dump, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree interface{}
+	err = yaml.Unmarshal(dump, &tree)
+	if err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// Decode decodes the tree into the value v points to the same way YAML does.
+func Decode(tree interface{}, v interface{}) error {
+	dump, err := yaml.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	return yaml.Unmarshal(dump, v)
+}
@@ -0,0 +1,57 @@
+package tree_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+
+	"go-vcr/internal/tree"
+)
+
+type value struct {
+	Name  string
+	Born  time.Time
+	Float float64
+	Map   map[int]string
+}
+
+func TestOf(t *testing.T) {
+	values := []interface{}{
+		nil,
+		"Alice",
+		int64(math.MaxInt64),
+		uint64(math.MaxUint64),
+		int8(5),
+		1.0,
+		[]interface{}{1, "two"},
+		map[interface{}]interface{}{"key": 1.5},
+		value{Name: "Alice", Born: time.Date(1990, time.March, 5, 0, 0, 0, 0, time.UTC), Float: 2, Map: map[int]string{1: "one"}},
+	}
+
+	for _, v := range values {
+		dump, err := yaml.Marshal(v)
+		assert.Nil(t, err)
+
+		var expected interface{}
+		assert.Nil(t, yaml.Unmarshal(dump, &expected))
+
+		actual, err := tree.Of(v)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	v := value{Name: "Alice", Float: 0.5, Map: map[int]string{1: "one"}}
+
+	tr, err := tree.Of(v)
+	assert.Nil(t, err)
+
+	restored := value{}
+	err = tree.Decode(tr, &restored)
+	assert.Nil(t, err)
+	assert.Equal(t, v, restored)
+}
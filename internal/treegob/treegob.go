@@ -0,0 +1,129 @@
+// Package treegob converts the trees decoded by YAML to the nodes
+// gob encodes without registering the types stored in interfaces, and back.
+package treegob
+
+import (
+	"fmt"
+	"math"
+)
+
+type kind uint8
+
+const (
+	kindNull kind = iota
+	kindString
+	kindInt
+	kindUint
+	kindFloat
+	kindBool
+	kindList
+	kindMap
+)
+
+// Node is the tree in the form gob can encode.
+// The map items hold the keys and the values interleaved.
+type Node struct {
+	Kind  kind
+	Str   string
+	Int   int64
+	Uint  uint64
+	Float float64
+	Bool  bool
+	Items []Node
+}
+
+// NodeOf converts the tree to the node.
+func NodeOf(tree interface{}) (Node, error) {
+	switch v := tree.(type) {
+	case nil:
+		return Node{Kind: kindNull}, nil
+	case string:
+		return Node{Kind: kindString, Str: v}, nil
+	case int:
+		return Node{Kind: kindInt, Int: int64(v)}, nil
+	case int64:
+		return Node{Kind: kindInt, Int: v}, nil
+	case uint64:
+		return Node{Kind: kindUint, Uint: v}, nil
+	case float64:
+		return Node{Kind: kindFloat, Float: v}, nil
+	case bool:
+		return Node{Kind: kindBool, Bool: v}, nil
+	case []interface{}:
+		n := Node{Kind: kindList, Items: make([]Node, len(v))}
+		for i := range v {
+			item, err := NodeOf(v[i])
+			if err != nil {
+				return Node{}, err
+			}
+			n.Items[i] = item
+		}
+		return n, nil
+	case map[interface{}]interface{}:
+		n := Node{Kind: kindMap, Items: make([]Node, 0, 2*len(v))}
+		for key, value := range v {
+			keyNode, err := NodeOf(key)
+			if err != nil {
+				return Node{}, err
+			}
+			valueNode, err := NodeOf(value)
+			if err != nil {
+				return Node{}, err
+			}
+			n.Items = append(n.Items, keyNode, valueNode)
+		}
+		return n, nil
+	}
+
+	return Node{}, fmt.Errorf("treegob: %T isn't a tree", tree)
+}
+
+// Tree restores the tree the same way YAML decodes it.
+func (n Node) Tree() (interface{}, error) {
+	switch n.Kind {
+	case kindNull:
+		return nil, nil
+	case kindString:
+		return n.Str, nil
+	case kindInt:
+		if n.Int >= math.MinInt && n.Int <= math.MaxInt {
+			return int(n.Int), nil
+		}
+		return n.Int, nil
+	case kindUint:
+		return n.Uint, nil
+	case kindFloat:
+		return n.Float, nil
+	case kindBool:
+		return n.Bool, nil
+	case kindList:
+		s := make([]interface{}, len(n.Items))
+		for i := range n.Items {
+			item, err := n.Items[i].Tree()
+			if err != nil {
+				return nil, err
+			}
+			s[i] = item
+		}
+		return s, nil
+	case kindMap:
+		if len(n.Items)%2 != 0 {
+			return nil, fmt.Errorf("treegob: map has a key without value")
+		}
+		m := make(map[interface{}]interface{}, len(n.Items)/2)
+		for i := 0; i < len(n.Items); i += 2 {
+			key, err := n.Items[i].Tree()
+			if err != nil {
+				return nil, err
+			}
+			value, err := n.Items[i+1].Tree()
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("treegob: unknown node kind %d", n.Kind)
+}
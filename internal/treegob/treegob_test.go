@@ -0,0 +1,42 @@
+package treegob_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-vcr/internal/treegob"
+)
+
+func TestNodeOfAndTree(t *testing.T) {
+	tree := map[interface{}]interface{}{
+		"name":  "Alice",
+		"int":   math.MaxInt64,
+		"uint":  uint64(math.MaxUint64),
+		"float": math.Inf(1),
+		"nil":   nil,
+		"list":  []interface{}{true, false},
+		1:       "one",
+	}
+
+	n, err := treegob.NodeOf(tree)
+	assert.Nil(t, err)
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, gob.NewEncoder(buf).Encode(n))
+
+	var decoded treegob.Node
+	assert.Nil(t, gob.NewDecoder(buf).Decode(&decoded))
+
+	restored, err := decoded.Tree()
+	assert.Nil(t, err)
+	assert.Equal(t, tree, restored)
+}
+
+func TestNodeOfNotTree(t *testing.T) {
+	_, err := treegob.NodeOf(struct{}{})
+	assert.NotNil(t, err)
+}
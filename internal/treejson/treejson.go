@@ -0,0 +1,164 @@
+// Package treejson converts the trees decoded by YAML to the values
+// encoding/json encodes losslessly and back.
+//
+// The map keys are stringified and the integer and boolean ones are restored.
+// The strings which aren't valid UTF-8 are encoded as {"!!binary": base64},
+// the maps having the single key starting with "!!" are encoded as
+// {"!!map": map}, so they aren't confused with the binary strings.
+package treejson
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	tagBinary = "!!binary"
+	tagMap    = "!!map"
+)
+
+// Encode converts the tree to the value encoding/json encodes losslessly.
+func Encode(tree interface{}) (interface{}, error) {
+	switch v := tree.(type) {
+	case string:
+		if !utf8.ValidString(v) {
+			return map[string]interface{}{tagBinary: base64.StdEncoding.EncodeToString([]byte(v))}, nil
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			keyStr, ok := key.(string)
+			if !ok {
+				keyStr = fmt.Sprint(key)
+			}
+			if !utf8.ValidString(keyStr) {
+				return nil, fmt.Errorf("treejson: map key %q isn't valid UTF-8", keyStr)
+			}
+
+			jsonValue, err := Encode(value)
+			if err != nil {
+				return nil, err
+			}
+			m[keyStr] = jsonValue
+		}
+		if isTagged(m) {
+			return map[string]interface{}{tagMap: m}, nil
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i := range v {
+			jsonValue, err := Encode(v[i])
+			if err != nil {
+				return nil, err
+			}
+			s[i] = jsonValue
+		}
+		return s, nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("treejson: %v can't be encoded to JSON", v)
+		}
+	}
+
+	return tree, nil
+}
+
+// Decode converts the value decoded by encoding/json with UseNumber back to the tree.
+// The JSON numbers are turned into the integers or floats the same way YAML decodes them.
+func Decode(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if isTagged(v) {
+			return decodeTagged(v)
+		}
+
+		m := make(map[interface{}]interface{}, len(v))
+		for key, value := range v {
+			tree, err := Decode(value)
+			if err != nil {
+				return nil, err
+			}
+			m[decodeKey(key)] = tree
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i := range v {
+			tree, err := Decode(v[i])
+			if err != nil {
+				return nil, err
+			}
+			s[i] = tree
+		}
+		return s, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			if i >= math.MinInt && i <= math.MaxInt {
+				return int(i), nil
+			}
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		return v.Float64()
+	}
+
+	return v, nil
+}
+
+func isTagged(m map[string]interface{}) bool {
+	if len(m) != 1 {
+		return false
+	}
+
+	for key := range m {
+		return strings.HasPrefix(key, "!!")
+	}
+
+	return false
+}
+
+func decodeTagged(m map[string]interface{}) (interface{}, error) {
+	if s, ok := m[tagBinary].(string); ok {
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("treejson: %s: %w", tagBinary, err)
+		}
+		return string(data), nil
+	}
+
+	if inner, ok := m[tagMap].(map[string]interface{}); ok {
+		tree := make(map[interface{}]interface{}, len(inner))
+		for key, value := range inner {
+			valueTree, err := Decode(value)
+			if err != nil {
+				return nil, err
+			}
+			tree[decodeKey(key)] = valueTree
+		}
+		return tree, nil
+	}
+
+	return nil, fmt.Errorf("treejson: unknown tagged value %v", m)
+}
+
+// decodeKey restores the integer and boolean map keys stringified by JSON.
+// Only the keys encoded back to the same string are restored,
+// so the string keys are decoded by YAML the same anyway.
+func decodeKey(key string) interface{} {
+	if i, err := strconv.Atoi(key); err == nil && strconv.Itoa(i) == key {
+		return i
+	}
+	if b, err := strconv.ParseBool(key); err == nil && strconv.FormatBool(b) == key {
+		return b
+	}
+
+	return key
+}
@@ -0,0 +1,51 @@
+package treejson_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-vcr/internal/treejson"
+)
+
+func TestEncodeAndDecode(t *testing.T) {
+	tree := map[interface{}]interface{}{
+		"name":   "Alice",
+		"int":    math.MaxInt64,
+		"uint":   uint64(math.MaxUint64),
+		"float":  0.1,
+		"binary": string([]byte{0xff, 0xfe, 0x00, 0x80}),
+		"map":    map[interface{}]interface{}{1: "one", true: "yes"},
+		"tagged": map[interface{}]interface{}{"!!binary": "not binary"},
+		"list":   []interface{}{nil, false},
+	}
+
+	v, err := treejson.Encode(tree)
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(v)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"binary":{"!!binary":"//4AgA=="}`)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded interface{}
+	assert.Nil(t, decoder.Decode(&decoded))
+
+	restored, err := treejson.Decode(decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, tree, restored)
+}
+
+func TestEncodeNotFinite(t *testing.T) {
+	_, err := treejson.Encode(math.Inf(1))
+	assert.NotNil(t, err)
+}
+
+func TestDecodeUnknownTag(t *testing.T) {
+	_, err := treejson.Decode(map[string]interface{}{"!!unknown": "value"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,49 @@
+package track
+
+import (
+	"context"
+)
+
+// KeyArgs are the args used in the key and dumps.
+// Contexts are excluded from them, since they aren't a part of the call data.
+func (track *Track) KeyArgs() []interface{} {
+	if track.args == nil {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(track.args))
+	for _, arg := range track.args {
+		if _, ok := arg.(context.Context); ok {
+			continue
+		}
+		args = append(args, arg)
+	}
+
+	return args
+}
+
+// Context is the context the track is called with.
+// It's the first context in the args or the context of the track
+// which results are set by ResultsAs.
+func (track *Track) Context() context.Context {
+	if track.ctx != nil {
+		return track.ctx
+	}
+
+	for _, arg := range track.args {
+		if ctx, ok := arg.(context.Context); ok {
+			return ctx
+		}
+	}
+
+	return nil
+}
+
+func (track *Track) contextErr() error {
+	ctx := track.Context()
+	if ctx == nil {
+		return nil
+	}
+
+	return ctx.Err()
+}
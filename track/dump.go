@@ -0,0 +1,107 @@
+package track
+
+import (
+	"time"
+
+	"go-vcr/internal/tree"
+)
+
+// Dump is the codec-neutral view of the track for the cassete codecs.
+// The args, results and panic are the trees the values are decoded to by YAML:
+// nil, string, int, int64, uint64, float64, bool, []interface{}
+// and map[interface{}]interface{}, so any codec able to encode these types
+// keeps the same values as YAML.
+type Dump struct {
+	Name       string
+	Seq        int
+	Func       string
+	Key        Key
+	KeyVersion int
+
+	Args    []interface{}
+	Results []interface{}
+
+	IsRecorded bool
+	Duration   time.Duration
+	Panic      interface{}
+}
+
+func (track *Track) Dump() (Dump, error) {
+	tr := track.forYAML()
+
+	args, err := treesOf(tr.Args)
+	if err != nil {
+		return Dump{}, err
+	}
+	results, err := treesOf(tr.Results)
+	if err != nil {
+		return Dump{}, err
+	}
+
+	var p interface{}
+	if tr.Panic != nil {
+		p, err = tree.Of(tr.Panic)
+		if err != nil {
+			return Dump{}, err
+		}
+	}
+
+	return Dump{
+		Name:       tr.Name,
+		Seq:        tr.Seq,
+		Func:       tr.Func,
+		Key:        tr.Key,
+		KeyVersion: tr.KeyVersion,
+
+		Args:    args,
+		Results: results,
+
+		IsRecorded: tr.IsRecorded,
+		Duration:   tr.Duration,
+		Panic:      p,
+	}, nil
+}
+
+// Restore sets the track from the dump the same way UnmarshalYAML does.
+func (track *Track) Restore(d Dump) error {
+	var p *panicForYAML
+	if d.Panic != nil {
+		p = new(panicForYAML)
+		err := tree.Decode(d.Panic, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return track.restore(&trackForYAML{
+		Name:       d.Name,
+		Seq:        d.Seq,
+		Func:       d.Func,
+		Key:        d.Key,
+		KeyVersion: d.KeyVersion,
+
+		Args:    d.Args,
+		Results: d.Results,
+
+		IsRecorded: d.IsRecorded,
+		Duration:   d.Duration,
+		Panic:      p,
+	})
+}
+
+func treesOf(values []interface{}) ([]interface{}, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	trees := make([]interface{}, len(values))
+	for i := range values {
+		t, err := tree.Of(values[i])
+		if err != nil {
+			return nil, err
+		}
+		trees[i] = t
+	}
+
+	return trees, nil
+}
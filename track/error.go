@@ -0,0 +1,139 @@
+package track
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+var errorRegistry = struct {
+	sentinels map[string]error
+	types     map[string]reflect.Type
+
+	mutex sync.RWMutex
+}{
+	sentinels: make(map[string]error),
+	types:     make(map[string]reflect.Type),
+}
+
+// RegisterError makes the sentinel error be restored by identity,
+// so errors.Is works for replayed errors.
+func RegisterError(name string, sentinel error) {
+	errorRegistry.mutex.Lock()
+	defer errorRegistry.mutex.Unlock()
+
+	errorRegistry.sentinels[name] = sentinel
+}
+
+// RegisterErrorType makes errors of the same concrete type as example be
+// restored with their fields, so errors.As works for replayed errors.
+func RegisterErrorType(example error) {
+	errorRegistry.mutex.Lock()
+	defer errorRegistry.mutex.Unlock()
+
+	t := reflect.TypeOf(example)
+	errorRegistry.types[t.String()] = t
+}
+
+func sentinelName(err error) (string, bool) {
+	errorRegistry.mutex.RLock()
+	defer errorRegistry.mutex.RUnlock()
+
+	if !reflect.TypeOf(err).Comparable() {
+		return "", false
+	}
+
+	for name, sentinel := range errorRegistry.sentinels {
+		if err == sentinel {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+func lookupSentinel(name string) (error, bool) {
+	errorRegistry.mutex.RLock()
+	defer errorRegistry.mutex.RUnlock()
+
+	sentinel, ok := errorRegistry.sentinels[name]
+	return sentinel, ok
+}
+
+func lookupErrorType(name string) (reflect.Type, bool) {
+	errorRegistry.mutex.RLock()
+	defer errorRegistry.mutex.RUnlock()
+
+	t, ok := errorRegistry.types[name]
+	return t, ok
+}
+
+// ReplayedError stands for a recorded error whose type isn't registered.
+type ReplayedError struct {
+	Message string
+	Type    string
+	Wrapped error
+}
+
+func (e *ReplayedError) Error() string {
+	return e.Message
+}
+
+func (e *ReplayedError) Unwrap() error {
+	return e.Wrapped
+}
+
+type errorForYAML struct {
+	Message  string
+	Type     string
+	Sentinel string        `yaml:",omitempty"`
+	Value    interface{}   `yaml:",omitempty"`
+	Wrapped  *errorForYAML `yaml:",omitempty"`
+}
+
+func newErrorForYAML(err error) *errorForYAML {
+	if err == nil {
+		return nil
+	}
+
+	e := &errorForYAML{
+		Message: err.Error(),
+		Type:    reflect.TypeOf(err).String(),
+	}
+
+	if name, ok := sentinelName(err); ok {
+		e.Sentinel = name
+		return e
+	}
+	if _, ok := lookupErrorType(e.Type); ok {
+		e.Value = err
+	}
+
+	e.Wrapped = newErrorForYAML(errors.Unwrap(err))
+
+	return e
+}
+
+func (e *errorForYAML) error() error {
+	if e == nil {
+		return nil
+	}
+
+	if sentinel, ok := lookupSentinel(e.Sentinel); ok {
+		return sentinel
+	}
+	if t, ok := lookupErrorType(e.Type); ok && e.Value != nil {
+		v, err := decodeAs(e.Value, t)
+		if err == nil {
+			return v.Interface().(error)
+		}
+	}
+
+	return &ReplayedError{
+		Message: e.Message,
+		Type:    e.Type,
+		Wrapped: e.Wrapped.error(),
+	}
+}
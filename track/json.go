@@ -0,0 +1,133 @@
+package track
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"go-vcr/internal/treejson"
+)
+
+// trackForJSON is Dump with the trees encoded by treejson.
+type trackForJSON struct {
+	Name       string `json:"name,omitempty"`
+	Seq        int    `json:"seq,omitempty"`
+	Func       string `json:"func,omitempty"`
+	Key        Key    `json:"key"`
+	KeyVersion int    `json:"keyversion"`
+
+	Args    []interface{} `json:"args"`
+	Results []interface{} `json:"results"`
+
+	IsRecorded bool          `json:"isrecorded"`
+	Duration   time.Duration `json:"duration"`
+	Panic      interface{}   `json:"panic,omitempty"`
+}
+
+func (track *Track) MarshalJSON() ([]byte, error) {
+	d, err := track.Dump()
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := encodeJSONValues(d.Args)
+	if err != nil {
+		return nil, err
+	}
+	results, err := encodeJSONValues(d.Results)
+	if err != nil {
+		return nil, err
+	}
+	p, err := treejson.Encode(d.Panic)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(trackForJSON{
+		Name:       d.Name,
+		Seq:        d.Seq,
+		Func:       d.Func,
+		Key:        d.Key,
+		KeyVersion: d.KeyVersion,
+
+		Args:    args,
+		Results: results,
+
+		IsRecorded: d.IsRecorded,
+		Duration:   d.Duration,
+		Panic:      p,
+	})
+}
+
+func (track *Track) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	tr := new(trackForJSON)
+	err := decoder.Decode(tr)
+	if err != nil {
+		return err
+	}
+
+	args, err := decodeJSONValues(tr.Args)
+	if err != nil {
+		return err
+	}
+	results, err := decodeJSONValues(tr.Results)
+	if err != nil {
+		return err
+	}
+	p, err := treejson.Decode(tr.Panic)
+	if err != nil {
+		return err
+	}
+
+	return track.Restore(Dump{
+		Name:       tr.Name,
+		Seq:        tr.Seq,
+		Func:       tr.Func,
+		Key:        tr.Key,
+		KeyVersion: tr.KeyVersion,
+
+		Args:    args,
+		Results: results,
+
+		IsRecorded: tr.IsRecorded,
+		Duration:   tr.Duration,
+		Panic:      p,
+	})
+}
+
+func encodeJSONValues(trees []interface{}) ([]interface{}, error) {
+	if trees == nil {
+		return nil, nil
+	}
+
+	values := make([]interface{}, len(trees))
+	for i := range trees {
+		v, err := treejson.Encode(trees[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+func decodeJSONValues(values []interface{}) ([]interface{}, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	trees := make([]interface{}, len(values))
+	for i := range values {
+		t, err := treejson.Decode(values[i])
+		if err != nil {
+			return nil, err
+		}
+		trees[i] = t
+	}
+
+	return trees, nil
+}
@@ -0,0 +1,125 @@
+package track
+
+import (
+	"encoding"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KeyVersion is the version of the key encoding.
+// The tracks dumped with another version can't be restored, as the types
+// of their args are lost by the dump and the key can't be derived again.
+const KeyVersion = 2
+
+// encodeArgs encodes the args with their types, so the encoding
+// doesn't depend on the way the values were decoded from a dump.
+func encodeArgs(args []interface{}) string {
+	var b strings.Builder
+
+	b.WriteString("[")
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		encodeValue(&b, reflect.ValueOf(arg), make(map[uintptr]bool))
+	}
+	b.WriteString("]")
+
+	return b.String()
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func encodeValue(b *strings.Builder, v reflect.Value, visited map[uintptr]bool) {
+	if !v.IsValid() {
+		b.WriteString("nil")
+		return
+	}
+
+	t := v.Type()
+	if t.Implements(textMarshalerType) && (t.Kind() != reflect.Ptr || !v.IsNil()) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err == nil {
+			b.WriteString(t.String() + "(" + strconv.Quote(string(text)) + ")")
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b.WriteString(t.String() + "(" + strconv.FormatBool(v.Bool()) + ")")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(t.String() + "(" + strconv.FormatInt(v.Int(), 10) + ")")
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		b.WriteString(t.String() + "(" + strconv.FormatUint(v.Uint(), 10) + ")")
+	case reflect.Float32, reflect.Float64:
+		b.WriteString(t.String() + "(" + strconv.FormatFloat(v.Float(), 'g', -1, 64) + ")")
+	case reflect.Complex64, reflect.Complex128:
+		b.WriteString(t.String() + strconv.FormatComplex(v.Complex(), 'g', -1, 128))
+	case reflect.String:
+		b.WriteString(t.String() + "(" + strconv.Quote(v.String()) + ")")
+	case reflect.Interface:
+		encodeValue(b, v.Elem(), visited)
+	case reflect.Ptr:
+		if v.IsNil() {
+			b.WriteString("(" + t.String() + ")(nil)")
+			return
+		}
+		if visited[v.Pointer()] {
+			b.WriteString("(" + t.String() + ")(cycle)")
+			return
+		}
+		visited[v.Pointer()] = true
+		b.WriteString("&")
+		encodeValue(b, v.Elem(), visited)
+		delete(visited, v.Pointer())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			b.WriteString(t.String() + "(nil)")
+			return
+		}
+		b.WriteString(t.String() + "{")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			encodeValue(b, v.Index(i), visited)
+		}
+		b.WriteString("}")
+	case reflect.Map:
+		if v.IsNil() {
+			b.WriteString(t.String() + "(nil)")
+			return
+		}
+		entries := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var entry strings.Builder
+			encodeValue(&entry, iter.Key(), visited)
+			entry.WriteString(": ")
+			encodeValue(&entry, iter.Value(), visited)
+			entries = append(entries, entry.String())
+		}
+		sort.Strings(entries)
+		b.WriteString(t.String() + "{" + strings.Join(entries, ", ") + "}")
+	case reflect.Struct:
+		b.WriteString(t.String() + "{")
+		written := 0
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if written > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(t.Field(i).Name + ": ")
+			encodeValue(b, v.Field(i), visited)
+			written++
+		}
+		b.WriteString("}")
+	default:
+		b.WriteString(t.String() + "(?)")
+	}
+}
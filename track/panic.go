@@ -0,0 +1,80 @@
+package track
+
+import (
+	"runtime/debug"
+)
+
+type recordedPanic struct {
+	value interface{}
+	stack string
+}
+
+// CapturePanic makes Record capture the panic of the function instead of propagating it.
+// The captured panic is raised again by Playback.
+func (track *Track) CapturePanic() *Track {
+	track.capturePanic = true
+	return track
+}
+
+func (track *Track) Panicked() bool {
+	return track.panic != nil
+}
+
+// PanicStack is the stack of the goroutine at the moment of the captured panic.
+func (track *Track) PanicStack() string {
+	if track.panic == nil {
+		return ""
+	}
+
+	return track.panic.stack
+}
+
+// Repanic raises the captured panic again if there is one.
+func (track *Track) Repanic() {
+	if track.panic != nil {
+		panic(track.panic.value)
+	}
+}
+
+func (track *Track) recoverPanic() {
+	value := recover()
+	if value == nil {
+		return
+	}
+
+	track.panic = &recordedPanic{
+		value: value,
+		stack: string(debug.Stack()),
+	}
+	track.isRecorded = true
+}
+
+type panicForYAML struct {
+	Value interface{}   `yaml:",omitempty"`
+	Error *errorForYAML `yaml:",omitempty"`
+	Stack string
+}
+
+func newPanicForYAML(p *recordedPanic) *panicForYAML {
+	if p == nil {
+		return nil
+	}
+
+	if err, ok := p.value.(error); ok {
+		return &panicForYAML{Error: newErrorForYAML(err), Stack: p.stack}
+	}
+
+	return &panicForYAML{Value: p.value, Stack: p.stack}
+}
+
+func (p *panicForYAML) recordedPanic() *recordedPanic {
+	if p == nil {
+		return nil
+	}
+
+	if p.Error != nil {
+		return &recordedPanic{value: p.Error.error(), stack: p.Stack}
+	}
+
+	return &recordedPanic{value: p.Value, stack: p.Stack}
+}
@@ -1,9 +1,10 @@
 package track
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"reflect"
+	"runtime"
 	"time"
 )
 
@@ -11,15 +12,25 @@ var ErrNotFunc = errors.New("The first argument must have the type 'func'")
 var ErrWrongFuncSignature = errors.New("The args or results signagure doesn't match the function")
 var ErrTrackWasntRecorded = errors.New("Can't playback track that wasn't recorded")
 var ErrTrackRewritingProhibited = errors.New("Track rewriting is prohibited")
+var ErrUnsupportedKeyVersion = errors.New("Track key version is unsupported")
 
 type Track struct {
 	fn      typeF
+	fnType  string
+	name    string
+	key     Key
 	args    []interface{}
 	results []interface{}
+	ctx     context.Context
 
 	out        []reflect.Value
 	isRecorded bool
 	duration   time.Duration
+
+	capturePanic bool
+	panic        *recordedPanic
+
+	seq int
 }
 
 type Key string
@@ -32,41 +43,122 @@ func New() *Track {
 	return new(Track)
 }
 
+// Key identifies the calls of the same function with the same args.
+// The key of the track restored from a dump is the dumped one.
 func (track *Track) Key() Key {
+	if track.key != KeyEmpty {
+		return track.key
+	}
+
 	key := Key("")
-	if track.fn != nil {
-		key += Key(reflect.TypeOf(track.fn).String())
+	if name := track.Name(); name != "" {
+		key += Key(name + " ")
 	}
+	key += Key(track.FnType())
 	if track.args != nil {
-		key += Key(track.argsJSON())
+		key += Key(encodeArgs(track.KeyArgs()))
 	}
 
 	return key
 }
 
-func (track *Track) argsJSON() string {
-	argsJSON, _ := json.Marshal(track.args)
-	return string(argsJSON)
+// Name is the name given with Named or the package-qualified name of the function.
+func (track *Track) Name() string {
+	if track.name != "" {
+		return track.name
+	}
+
+	v := reflect.ValueOf(track.fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+
+	return runtime.FuncForPC(v.Pointer()).Name()
+}
+
+// FnType is the signature of the function or the signature restored from a dump.
+func (track *Track) FnType() string {
+	if track.fn == nil {
+		return track.fnType
+	}
+
+	return reflect.TypeOf(track.fn).String()
+}
+
+func (track *Track) Args() []interface{} {
+	return track.args
 }
 
 func (track *Track) Call(fn typeF) *Track {
 	track.fn = fn
+	track.flattenVariadicArgs()
+	return track
+}
+
+func (track *Track) Named(name string) *Track {
+	track.name = name
 	return track
 }
 
 func (track *Track) With(args ...interface{}) *Track {
 	track.args = args
+	track.flattenVariadicArgs()
 	return track
 }
 
+// flattenVariadicArgs expands the variadic args passed as a slice,
+// so the args are the same for f(a, b) and f([]T{a, b}...).
+func (track *Track) flattenVariadicArgs() {
+	t := reflect.TypeOf(track.fn)
+	if t == nil || t.Kind() != reflect.Func || !t.IsVariadic() || len(track.args) != t.NumIn() {
+		return
+	}
+
+	last := len(track.args) - 1
+	tail := reflect.ValueOf(track.args[last])
+	if !tail.IsValid() || !tail.Type().AssignableTo(t.In(last)) || tail.Type().AssignableTo(t.In(last).Elem()) {
+		return
+	}
+
+	args := make([]interface{}, last, last+tail.Len())
+	copy(args, track.args[:last])
+	for i := 0; i < tail.Len(); i++ {
+		args = append(args, tail.Index(i).Interface())
+	}
+
+	track.args = args
+}
+
 func (track *Track) ResultsIn(results ...interface{}) *Track {
 	track.results = results
 	return track
 }
 
+// ResultsAs returns the copy of the track which sets the results of trackSource
+// on playback and respects the context trackSource is called with.
+// The track itself isn't changed, so it can be played back concurrently.
 func (track *Track) ResultsAs(trackSource *Track) *Track {
-	track.results = trackSource.results
-	return track
+	played := *track
+	played.results = trackSource.results
+	played.ctx = trackSource.Context()
+	if played.fn == nil {
+		played.fn = trackSource.fn
+	}
+
+	return &played
+}
+
+// Seq is the sequence number of the track in the cassete it's recorded to.
+func (track *Track) Seq() int {
+	return track.seq
+}
+
+func (track *Track) SetSeq(seq int) {
+	track.seq = seq
+}
+
+func (track *Track) Duration() time.Duration {
+	return track.duration
 }
 
 func (track *Track) IsRecorded() bool {
@@ -85,9 +177,14 @@ func (track *Track) Playback(results ...interface{}) error {
 		}
 	}
 
-	track.setResults(results)
+	err := track.contextErr()
+	if err != nil {
+		return err
+	}
 
-	return nil
+	track.Repanic()
+
+	return track.setResults(results)
 }
 
 func (track *Track) Record() error {
@@ -100,6 +197,13 @@ func (track *Track) Record() error {
 		return err
 	}
 
+	// The context is checked before the call only, the finished call
+	// is recorded with whatever it returned, the context errors included.
+	err = track.contextErr()
+	if err != nil {
+		return err
+	}
+
 	track.do()
 
 	return nil
@@ -111,39 +215,90 @@ func (track *Track) setDurationSince(startTime time.Time) {
 
 func (track *Track) do() {
 	defer track.setDurationSince(time.Now())
+	if track.capturePanic {
+		defer track.recoverPanic()
+	}
 
-	in := track.getFnIn(track.args)
-	track.out = reflect.ValueOf(track.fn).Call(in)
+	track.out = track.call()
 	track.isRecorded = true
 
 	track.setResults(track.results)
 }
 
-func (track *Track) setResults(results []interface{}) {
+func (track *Track) setResults(results []interface{}) error {
 	if results == nil {
 		results = track.results
 	}
 
 	for i := range track.out {
-		reflect.ValueOf(results[i]).Elem().Set(track.out[i])
+		dst := reflect.ValueOf(results[i]).Elem()
+
+		out, err := track.result(i, track.resultType(i, dst.Type()))
+		if err != nil {
+			return err
+		}
+
+		dst.Set(out)
 	}
+
+	return nil
+}
+
+// resultType is the type declared by the function for the i-th result,
+// or the type of the destination if the function is unknown.
+func (track *Track) resultType(i int, dstType reflect.Type) reflect.Type {
+	if track.fn == nil {
+		return dstType
+	}
+
+	return reflect.TypeOf(track.fn).Out(i)
+}
+
+// result returns the i-th result converted to the type t.
+// Results restored from a dump lose their types, so they are decoded again.
+func (track *Track) result(i int, t reflect.Type) (reflect.Value, error) {
+	out := track.out[i]
+	if out.Type() == t {
+		return out, nil
+	}
+
+	return decodeAs(out.Interface(), t)
 }
 
 func (track *Track) CheckFn() error {
 	v := reflect.ValueOf(track.fn)
-	t := v.Type()
-
 	if v.Kind() != reflect.Func {
 		return ErrNotFunc
 	}
-	if t.NumIn() != len(track.args) || t.NumOut() != len(track.results) {
+
+	t := v.Type()
+	if t.NumOut() != len(track.results) {
 		return ErrWrongFuncSignature
 	}
 
-	for i := 0; i < t.NumIn(); i++ {
-		if t.In(i).String() != reflect.TypeOf(track.args[i]).String() {
+	numFixed := t.NumIn()
+	if t.IsVariadic() {
+		numFixed--
+		if len(track.args) < numFixed {
 			return ErrWrongFuncSignature
 		}
+	} else if len(track.args) != numFixed {
+		return ErrWrongFuncSignature
+	}
+
+	for i := 0; i < numFixed; i++ {
+		if !isAssignable(track.args[i], t.In(i)) {
+			return ErrWrongFuncSignature
+		}
+	}
+
+	if t.IsVariadic() {
+		elem := t.In(numFixed).Elem()
+		for _, arg := range track.args[numFixed:] {
+			if !isAssignable(arg, elem) {
+				return ErrWrongFuncSignature
+			}
+		}
 	}
 
 	err := track.checkResults(track.results)
@@ -158,7 +313,8 @@ func (track *Track) checkResults(results []interface{}) error {
 	t := reflect.TypeOf(track.fn)
 
 	for i := 0; i < t.NumOut(); i++ {
-		if t.Out(i).String() != reflect.TypeOf(results[i]).Elem().String() {
+		dst := reflect.ValueOf(results[i])
+		if dst.Kind() != reflect.Ptr || dst.IsNil() || !t.Out(i).AssignableTo(dst.Type().Elem()) {
 			return ErrWrongFuncSignature
 		}
 	}
@@ -166,12 +322,72 @@ func (track *Track) checkResults(results []interface{}) error {
 	return nil
 }
 
+// isAssignable reports whether arg can be passed as the value of type t.
+// Nil is accepted for the types having nil value, and the values
+// of the same kind are accepted if they are convertible to t.
+func isAssignable(arg interface{}, t reflect.Type) bool {
+	if arg == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return true
+		}
+
+		return false
+	}
+
+	argType := reflect.TypeOf(arg)
+	if argType.AssignableTo(t) {
+		return true
+	}
+
+	return argType.Kind() == t.Kind() && argType.ConvertibleTo(t)
+}
+
+// argValue is the value of arg accepted by isAssignable as the value of type t.
+func argValue(arg interface{}, t reflect.Type) reflect.Value {
+	if arg == nil {
+		return reflect.Zero(t)
+	}
+
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(t) {
+		return v
+	}
+
+	return v.Convert(t)
+}
+
+func (track *Track) call() []reflect.Value {
+	v := reflect.ValueOf(track.fn)
+	if !v.Type().IsVariadic() {
+		return v.Call(track.getFnIn(track.args))
+	}
+
+	return v.CallSlice(track.getVariadicFnIn(track.args))
+}
+
 func (track *Track) getFnIn(args []interface{}) []reflect.Value {
+	t := reflect.TypeOf(track.fn)
 	in := make([]reflect.Value, len(args))
 
 	for i := range args {
-		in[i] = reflect.ValueOf(args[i])
+		in[i] = argValue(args[i], t.In(i))
 	}
 
 	return in
 }
+
+// getVariadicFnIn collects the variadic args into the slice for CallSlice.
+func (track *Track) getVariadicFnIn(args []interface{}) []reflect.Value {
+	t := reflect.TypeOf(track.fn)
+	numFixed := t.NumIn() - 1
+
+	in := track.getFnIn(args[:numFixed])
+
+	tail := reflect.MakeSlice(t.In(numFixed), 0, len(args)-numFixed)
+	for _, arg := range args[numFixed:] {
+		tail = reflect.Append(tail, argValue(arg, t.In(numFixed).Elem()))
+	}
+
+	return append(in, tail)
+}
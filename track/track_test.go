@@ -1,9 +1,16 @@
 package track_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"gopkg.in/yaml.v2"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -162,23 +169,70 @@ func TestTrackIsRecorded(t *testing.T) {
 	})
 }
 
+func namedFn() {}
+
 func TestTrackKey(t *testing.T) {
 	t.Run("Key is", func(t *testing.T) {
 		t.Run("Empty string for new track", func(t *testing.T) {
 			tr := track.New()
 			assert.Equal(t, track.Key(""), tr.Key())
 		})
-		t.Run("Function signature for track calling fn", func(t *testing.T) {
-			tr := track.New().Call(emptyFn)
-			assert.Equal(t, track.Key("func()"), tr.Key())
+		t.Run("Function name + signature for track calling fn", func(t *testing.T) {
+			tr := track.New().Call(namedFn)
+			assert.Equal(t, track.Key("go-vcr/track_test.namedFn func()"), tr.Key())
 		})
-		t.Run("Function signature + arg values for track calling fn with args", func(t *testing.T) {
+		t.Run("Function name + signature + arg values for track calling fn with args", func(t *testing.T) {
 			argStr := "hey girl"
 			argInt := 5
 			tr := track.New().Call(func(string, int) {}).With(argStr, argInt)
-			assert.Equal(t, track.Key(fmt.Sprintf(`func(string, int)["%s",%d]`, argStr, argInt)), tr.Key())
+			assert.Equal(t, track.Key(fmt.Sprintf(`%s func(string, int)[string("%s"), int(%d)]`, tr.Name(), argStr, argInt)), tr.Key())
+		})
+		t.Run("Explicit name instead of function name", func(t *testing.T) {
+			tr := track.New().Call(emptyFn).Named("Empty")
+			assert.Equal(t, track.Key("Empty func()"), tr.Key())
 		})
 	})
+	t.Run("Different functions with the same signature have different keys", func(t *testing.T) {
+		getUser := func(int) string { return "get" }
+		deleteUser := func(int) string { return "delete" }
+
+		trGet := track.New().Call(getUser).With(1)
+		trDelete := track.New().Call(deleteUser).With(1)
+		assert.NotEqual(t, trGet.Key(), trDelete.Key())
+	})
+	t.Run("Arg types are a part of the key", func(t *testing.T) {
+		fn := func(interface{}) {}
+
+		trInt := track.New().Call(fn).With(5)
+		trInt64 := track.New().Call(fn).With(int64(5))
+		assert.NotEqual(t, trInt.Key(), trInt64.Key())
+	})
+	t.Run("Key is the same after restoring from dump", func(t *testing.T) {
+		fn := func(user, int64, map[string]int, *userID) {}
+		id := userID(7)
+
+		tr := track.New().Call(fn).With(user{Name: "Alice", Age: 30}, int64(5), map[string]int{"b": 2, "a": 1}, &id)
+		assert.Contains(t, string(tr.Key()), `map[string]int{string("a"): int(1), string("b"): int(2)}`)
+		tr.Record()
+
+		dump, _ := yaml.Marshal(tr)
+
+		trRestored := track.New()
+		err := yaml.Unmarshal(dump, trRestored)
+		assert.Nil(t, err)
+		assert.Equal(t, tr.Key(), trRestored.Key())
+	})
+	t.Run("Function name is restored from dump", func(t *testing.T) {
+		tr := track.New().Call(emptyFn).Named("Empty")
+		tr.Record()
+
+		dump, _ := yaml.Marshal(tr)
+
+		trRestored := track.New()
+		err := yaml.Unmarshal(dump, trRestored)
+		assert.Nil(t, err)
+		assert.Equal(t, "Empty", trRestored.Name())
+	})
 }
 
 func TestDumpAndRestore(t *testing.T) {
@@ -208,3 +262,460 @@ func TestDumpAndRestore(t *testing.T) {
 		assert.Equal(t, argStr, resultStr)
 	})
 }
+
+type user struct {
+	Name    string
+	Age     int64
+	Tags    []string
+	Friends map[string]int
+	Born    time.Time
+}
+
+type userID int
+
+func TestDumpAndRestoreResultTypes(t *testing.T) {
+	born := time.Date(1990, time.March, 5, 10, 0, 0, 0, time.UTC)
+	u := user{
+		Name:    "Alice",
+		Age:     30,
+		Tags:    []string{"admin"},
+		Friends: map[string]int{"Mary": 1},
+		Born:    born,
+	}
+
+	fn := func() (user, *user, int64, userID, []user, map[string]user, time.Time) {
+		return u, &u, 5, userID(7), []user{u}, map[string]user{"a": u}, born
+	}
+
+	var (
+		resultUser     user
+		resultPtr      *user
+		resultInt64    int64
+		resultID       userID
+		resultSlice    []user
+		resultMap      map[string]user
+		resultTime     time.Time
+		resultPointers = []interface{}{&resultUser, &resultPtr, &resultInt64, &resultID, &resultSlice, &resultMap, &resultTime}
+	)
+
+	tr := track.New().Call(fn).ResultsIn(resultPointers...)
+	err := tr.Record()
+	assert.Nil(t, err)
+
+	dump, err := yaml.Marshal(tr)
+	assert.Nil(t, err)
+
+	t.Run("Restored track without func", func(t *testing.T) {
+		trRestored := track.New()
+		err := yaml.Unmarshal(dump, trRestored)
+		assert.Nil(t, err)
+
+		resultUser, resultPtr, resultInt64, resultID = user{}, nil, 0, 0
+		resultSlice, resultMap, resultTime = nil, nil, time.Time{}
+
+		err = trRestored.Playback(resultPointers...)
+		assert.Nil(t, err)
+		assert.Equal(t, u, resultUser)
+		assert.Equal(t, &u, resultPtr)
+		assert.Equal(t, int64(5), resultInt64)
+		assert.Equal(t, userID(7), resultID)
+		assert.Equal(t, []user{u}, resultSlice)
+		assert.Equal(t, map[string]user{"a": u}, resultMap)
+		assert.True(t, born.Equal(resultTime))
+	})
+	t.Run("Restored track with func", func(t *testing.T) {
+		trRestored := track.New().Call(fn).ResultsIn(resultPointers...)
+		err := yaml.Unmarshal(dump, trRestored)
+		assert.Nil(t, err)
+
+		resultUser, resultInt64 = user{}, 0
+
+		err = trRestored.Playback()
+		assert.Nil(t, err)
+		assert.Equal(t, u, resultUser)
+		assert.Equal(t, int64(5), resultInt64)
+	})
+}
+
+var errNotFound = errors.New("not found")
+
+type codeError struct {
+	Code int
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprintf("code %d", e.Code)
+}
+
+func TestDumpAndRestoreErrors(t *testing.T) {
+	track.RegisterError("errNotFound", errNotFound)
+	track.RegisterErrorType(&codeError{})
+
+	restore := func(t *testing.T, errRecorded error) error {
+		fn := func() error { return errRecorded }
+
+		var resultErr error
+		tr := track.New().Call(fn).ResultsIn(&resultErr)
+		err := tr.Record()
+		assert.Nil(t, err)
+
+		dump, err := yaml.Marshal(tr)
+		assert.Nil(t, err)
+
+		trRestored := track.New()
+		err = yaml.Unmarshal(dump, trRestored)
+		assert.Nil(t, err)
+
+		resultErr = nil
+		err = trRestored.Playback(&resultErr)
+		assert.Nil(t, err)
+
+		return resultErr
+	}
+
+	t.Run("Nil error", func(t *testing.T) {
+		assert.Nil(t, restore(t, nil))
+	})
+	t.Run("Registered sentinel error", func(t *testing.T) {
+		err := restore(t, errNotFound)
+		assert.Equal(t, errNotFound, err)
+	})
+	t.Run("Wrapped sentinel error", func(t *testing.T) {
+		errRecorded := fmt.Errorf("get user: %w", errNotFound)
+
+		err := restore(t, errRecorded)
+		assert.Equal(t, errRecorded.Error(), err.Error())
+		assert.True(t, errors.Is(err, errNotFound))
+	})
+	t.Run("Registered error type", func(t *testing.T) {
+		err := restore(t, fmt.Errorf("call: %w", &codeError{Code: 404}))
+		assert.Equal(t, "call: code 404", err.Error())
+
+		var errCode *codeError
+		assert.True(t, errors.As(err, &errCode))
+		assert.Equal(t, 404, errCode.Code)
+	})
+	t.Run("Unregistered error keeps message and type", func(t *testing.T) {
+		err := restore(t, errors.New("boom"))
+		assert.Equal(t, "boom", err.Error())
+
+		var errReplayed *track.ReplayedError
+		assert.True(t, errors.As(err, &errReplayed))
+		assert.Equal(t, "*errors.errorString", errReplayed.Type)
+	})
+}
+
+func TestTrackPanic(t *testing.T) {
+	t.Run("Panic isn't captured by default", func(t *testing.T) {
+		tr := track.New().Call(func() { panic("boom") })
+		assert.PanicsWithValue(t, "boom", func() { tr.Record() })
+		assert.False(t, tr.IsRecorded())
+	})
+	t.Run("Captured panic is raised on playback", func(t *testing.T) {
+		tr := track.New().Call(func() { panic("boom") }).CapturePanic()
+
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.True(t, tr.IsRecorded())
+		assert.True(t, tr.Panicked())
+		assert.Contains(t, tr.PanicStack(), "panic")
+
+		assert.PanicsWithValue(t, "boom", func() { tr.Playback() })
+	})
+	t.Run("Captured panic is restored from dump", func(t *testing.T) {
+		fn := func() string { panic("boom") }
+		resultStr := ""
+		tr := track.New().Call(fn).ResultsIn(&resultStr).CapturePanic()
+		tr.Record()
+
+		dump, _ := yaml.Marshal(tr)
+
+		trRestored := track.New()
+		err := yaml.Unmarshal(dump, trRestored)
+		assert.Nil(t, err)
+		assert.Equal(t, tr.PanicStack(), trRestored.PanicStack())
+		assert.PanicsWithValue(t, "boom", func() { trRestored.Playback(&resultStr) })
+	})
+	t.Run("Captured error panic is restored from dump", func(t *testing.T) {
+		tr := track.New().Call(func() { panic(errors.New("boom")) }).CapturePanic()
+		tr.Record()
+
+		dump, _ := yaml.Marshal(tr)
+
+		trRestored := track.New()
+		yaml.Unmarshal(dump, trRestored)
+		assert.PanicsWithError(t, "boom", func() { trRestored.Playback() })
+	})
+}
+
+func TestTrackVariadic(t *testing.T) {
+	sprintf := func(format string, args ...interface{}) string { return fmt.Sprintf(format, args...) }
+	sum := func(base int, xs ...int) int {
+		for _, x := range xs {
+			base += x
+		}
+		return base
+	}
+
+	t.Run("Variadic args are passed", func(t *testing.T) {
+		resultStr := ""
+		tr := track.New().Call(sprintf).With("%s is %d", "Alice", 30).ResultsIn(&resultStr)
+
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.Equal(t, "Alice is 30", resultStr)
+	})
+	t.Run("Empty variadic tail", func(t *testing.T) {
+		result := 0
+		tr := track.New().Call(sum).With(1).ResultsIn(&result)
+
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, result)
+	})
+	t.Run("Nil in variadic tail of interfaces", func(t *testing.T) {
+		resultStr := ""
+		tr := track.New().Call(sprintf).With("%v", nil).ResultsIn(&resultStr)
+
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.Equal(t, "<nil>", resultStr)
+	})
+	t.Run("Wrong type in variadic tail", func(t *testing.T) {
+		result := 0
+		tr := track.New().Call(sum).With(1, 2, "three").ResultsIn(&result)
+
+		err := tr.Record()
+		assert.Equal(t, track.ErrWrongFuncSignature, err)
+	})
+	t.Run("Missing fixed args", func(t *testing.T) {
+		result := 0
+		tr := track.New().Call(sum).ResultsIn(&result)
+
+		err := tr.Record()
+		assert.Equal(t, track.ErrWrongFuncSignature, err)
+	})
+	t.Run("Variadic slice is flattened", func(t *testing.T) {
+		result := 0
+		tr := track.New().Call(sum).With(1, []int{2, 3}).ResultsIn(&result)
+		trFlat := track.New().Call(sum).With(1, 2, 3)
+		assert.Equal(t, trFlat.Key(), tr.Key())
+
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.Equal(t, 6, result)
+	})
+}
+
+func TestTrackAssignableTypes(t *testing.T) {
+	t.Run("Arg implementing interface param", func(t *testing.T) {
+		fn := func(r io.Reader) string {
+			data, _ := io.ReadAll(r)
+			return string(data)
+		}
+
+		resultStr := ""
+		tr := track.New().Call(fn).With(bytes.NewBufferString("hey")).ResultsIn(&resultStr)
+
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.Equal(t, "hey", resultStr)
+	})
+	t.Run("Nil args of nillable params", func(t *testing.T) {
+		fn := func(*user, map[string]int, []int, io.Reader) bool { return true }
+
+		result := false
+		tr := track.New().Call(fn).With(nil, nil, nil, nil).ResultsIn(&result)
+
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.True(t, result)
+	})
+	t.Run("Nil arg of not nillable param", func(t *testing.T) {
+		fn := func(int) {}
+
+		err := track.New().Call(fn).With(nil).Record()
+		assert.Equal(t, track.ErrWrongFuncSignature, err)
+	})
+	t.Run("Arg convertible to named type", func(t *testing.T) {
+		fn := func(id userID) userID { return id }
+
+		result := userID(0)
+		tr := track.New().Call(fn).With(7).ResultsIn(&result)
+
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.Equal(t, userID(7), result)
+	})
+	t.Run("Arg convertible to another kind is rejected", func(t *testing.T) {
+		fn := func(string) {}
+
+		err := track.New().Call(fn).With(65).Record()
+		assert.Equal(t, track.ErrWrongFuncSignature, err)
+	})
+	t.Run("Result destination of interface type", func(t *testing.T) {
+		fn := func() *bytes.Buffer { return bytes.NewBufferString("hey") }
+
+		var result io.Reader
+		tr := track.New().Call(fn).ResultsIn(&result)
+
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.Equal(t, bytes.NewBufferString("hey"), result)
+	})
+	t.Run("Nil result destination is rejected", func(t *testing.T) {
+		fn := func() int { return 0 }
+
+		err := track.New().Call(fn).ResultsIn(nil).Record()
+		assert.Equal(t, track.ErrWrongFuncSignature, err)
+	})
+}
+
+type ctxKey struct{}
+
+func TestTrackContext(t *testing.T) {
+	fn := func(ctx context.Context, name string) string {
+		value, _ := ctx.Value(ctxKey{}).(string)
+		return value + " " + name
+	}
+
+	t.Run("Context is excluded from key and dump", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "hey")
+
+		tr := track.New().Call(fn).With(ctx, "Alice")
+		assert.True(t, strings.HasSuffix(string(tr.Key()), `string) string[string("Alice")]`))
+
+		trOther := track.New().Call(fn).With(context.Background(), "Alice")
+		assert.Equal(t, trOther.Key(), tr.Key())
+		assert.Equal(t, []interface{}{"Alice"}, tr.KeyArgs())
+
+		resultStr := ""
+		tr.ResultsIn(&resultStr).Record()
+
+		dump, _ := yaml.Marshal(tr)
+		assert.Contains(t, string(dump), "args:\n- Alice\n")
+	})
+	t.Run("Live context is passed on record", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "hey")
+
+		resultStr := ""
+		err := track.New().Call(fn).With(ctx, "Alice").ResultsIn(&resultStr).Record()
+		assert.Nil(t, err)
+		assert.Equal(t, "hey Alice", resultStr)
+	})
+	t.Run("Cancelled context isn't recorded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		calledCount := 0
+		fn := func(context.Context) { calledCount++ }
+
+		tr := track.New().Call(fn).With(ctx)
+		err := tr.Record()
+		assert.Equal(t, context.Canceled, err)
+		assert.False(t, tr.IsRecorded())
+		assert.Equal(t, 0, calledCount)
+	})
+	t.Run("Call finished after the context is cancelled is recorded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		fn := func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		var resultErr error
+		tr := track.New().Call(fn).With(ctx).ResultsIn(&resultErr)
+		err := tr.Record()
+		assert.Nil(t, err)
+		assert.True(t, tr.IsRecorded())
+		assert.Equal(t, context.DeadlineExceeded, resultErr)
+
+		resultErr = nil
+		trCaller := track.New().Call(fn).With(context.Background()).ResultsIn(&resultErr)
+		err = tr.ResultsAs(trCaller).Playback()
+		assert.Nil(t, err)
+		assert.True(t, errors.Is(resultErr, context.DeadlineExceeded))
+	})
+	t.Run("Playback respects the context of the caller", func(t *testing.T) {
+		resultStr := ""
+		tr := track.New().Call(fn).With(context.Background(), "Alice").ResultsIn(&resultStr)
+		tr.Record()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		trCaller := track.New().Call(fn).With(ctx, "Alice").ResultsIn(&resultStr)
+		err := tr.ResultsAs(trCaller).Playback()
+		assert.Equal(t, context.Canceled, err)
+	})
+}
+
+func TestDumpAndRestoreJSON(t *testing.T) {
+	u := user{Name: "Alice", Age: 1 << 60, Tags: []string{"admin"}, Friends: map[string]int{"1": 1}, Born: time.Date(1990, time.March, 5, 10, 0, 0, 0, time.UTC)}
+	fn := func(id userID) (user, float64, error) { return u, 0.5, fmt.Errorf("get: %w", errNotFound) }
+	track.RegisterError("errNotFound", errNotFound)
+
+	var (
+		resultUser  user
+		resultFloat float64
+		resultErr   error
+	)
+	tr := track.New().Call(fn).With(userID(7)).ResultsIn(&resultUser, &resultFloat, &resultErr)
+	err := tr.Record()
+	assert.Nil(t, err)
+
+	dump, err := json.Marshal(tr)
+	assert.Nil(t, err)
+	assert.Contains(t, string(dump), `"name":"Alice"`)
+
+	trRestored := track.New()
+	err = json.Unmarshal(dump, trRestored)
+	assert.Nil(t, err)
+	assert.Equal(t, tr.Key(), trRestored.Key())
+	assert.Equal(t, tr.Duration(), trRestored.Duration())
+
+	resultUser, resultFloat, resultErr = user{}, 0, nil
+	err = trRestored.Playback(&resultUser, &resultFloat, &resultErr)
+	assert.Nil(t, err)
+	assert.Equal(t, u, resultUser)
+	assert.Equal(t, 0.5, resultFloat)
+	assert.EqualError(t, resultErr, "get: not found")
+	assert.True(t, errors.Is(resultErr, errNotFound))
+
+	dumpRestored, _ := json.Marshal(trRestored)
+	assert.Equal(t, string(dump), string(dumpRestored))
+}
+
+func TestDumpAndRestoreJSONBinary(t *testing.T) {
+	binary := string([]byte{0xff, 0xfe, 0x00, 0x80})
+	fn := func(argStr string) (string, []byte, map[string]string) {
+		return binary, []byte(binary), map[string]string{"!!binary": "not binary"}
+	}
+
+	var (
+		resultStr   string
+		resultBytes []byte
+		resultMap   map[string]string
+	)
+	tr := track.New().Call(fn).With(binary).ResultsIn(&resultStr, &resultBytes, &resultMap)
+	err := tr.Record()
+	assert.Nil(t, err)
+
+	dump, err := json.Marshal(tr)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(dump), "\\ufffd")
+
+	trRestored := track.New()
+	err = json.Unmarshal(dump, trRestored)
+	assert.Nil(t, err)
+	assert.Equal(t, tr.Key(), trRestored.Key())
+	assert.Equal(t, tr.KeyArgs(), trRestored.KeyArgs())
+
+	resultStr, resultBytes, resultMap = "", nil, nil
+	err = trRestored.Playback(&resultStr, &resultBytes, &resultMap)
+	assert.Nil(t, err)
+	assert.Equal(t, binary, resultStr)
+	assert.Equal(t, []byte(binary), resultBytes)
+	assert.Equal(t, map[string]string{"!!binary": "not binary"}, resultMap)
+}
@@ -1,33 +1,54 @@
 package track
 
 import (
+	"fmt"
 	"reflect"
 	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
 type trackForYAML struct {
+	Name       string `yaml:",omitempty"`
+	Seq        int    `yaml:",omitempty"`
+	Func       string `yaml:",omitempty"`
+	Key        Key
+	KeyVersion int
+
 	Args    []interface{}
 	Results []interface{}
 
 	IsRecorded bool
 	Duration   time.Duration
+	Panic      *panicForYAML `yaml:",omitempty"`
 }
 
 func (track *Track) MarshalYAML() (interface{}, error) {
+	return track.forYAML(), nil
+}
+
+func (track *Track) forYAML() *trackForYAML {
 	results := make([]interface{}, len(track.out))
 	for i := range track.out {
-		results[i] = track.out[i].Interface()
+		results[i] = resultForYAML(track.out[i])
 	}
 
 	tr := trackForYAML{
-		Args:    track.args,
+		Name:       track.Name(),
+		Seq:        track.seq,
+		Func:       track.FnType(),
+		Key:        track.Key(),
+		KeyVersion: KeyVersion,
+
+		Args:    track.KeyArgs(),
 		Results: results,
 
 		IsRecorded: track.IsRecorded(),
 		Duration:   track.duration,
+		Panic:      newPanicForYAML(track.panic),
 	}
 
-	return tr, nil
+	return &tr
 }
 
 func (track *Track) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -37,13 +58,85 @@ func (track *Track) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	return track.restore(tr)
+}
+
+// restore sets the fields of the track decoded from a dump.
+func (track *Track) restore(tr *trackForYAML) error {
+	if tr.KeyVersion != KeyVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedKeyVersion, tr.KeyVersion)
+	}
+
+	if track.name == "" {
+		track.name = tr.Name
+	}
+	track.seq = tr.Seq
+	track.fnType = tr.Func
+	track.key = tr.Key
 	track.args = tr.Args
 	track.out = make([]reflect.Value, 0, len(tr.Results))
-	for _, result := range tr.Results {
-		track.out = append(track.out, reflect.ValueOf(result))
+	for i := range tr.Results {
+		track.out = append(track.out, reflect.ValueOf(&tr.Results[i]).Elem())
 	}
 	track.isRecorded = tr.IsRecorded
 	track.duration = tr.Duration
+	track.panic = tr.Panic.recordedPanic()
 
 	return nil
 }
+
+func resultForYAML(out reflect.Value) interface{} {
+	if out.Type() == errorType {
+		if out.IsNil() {
+			return nil
+		}
+
+		return newErrorForYAML(out.Interface().(error))
+	}
+
+	return out.Interface()
+}
+
+// decodeAs converts the value decoded by yaml into the type t
+// by encoding it back and decoding into the value of type t.
+func decodeAs(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+	if t == errorType {
+		return decodeError(value)
+	}
+	if t.Kind() == reflect.Interface && reflect.TypeOf(value).AssignableTo(t) {
+		return reflect.ValueOf(value).Convert(t), nil
+	}
+
+	dump, err := yaml.Marshal(value)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	ptr := reflect.New(t)
+	err = yaml.Unmarshal(dump, ptr.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return ptr.Elem(), nil
+}
+
+func decodeError(value interface{}) (reflect.Value, error) {
+	dump, err := yaml.Marshal(value)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	e := new(errorForYAML)
+	err = yaml.Unmarshal(dump, e)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	result := e.error()
+
+	return reflect.ValueOf(&result).Elem(), nil
+}
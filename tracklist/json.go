@@ -0,0 +1,27 @@
+package tracklist
+
+import (
+	"encoding/json"
+
+	"go-vcr/track"
+)
+
+type trackListForJSON struct {
+	Tracks []*track.Track `json:"tracks"`
+}
+
+func (t *TrackList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(trackListForJSON{Tracks: t.tracks})
+}
+
+func (t *TrackList) UnmarshalJSON(data []byte) error {
+	tl := new(trackListForJSON)
+	err := json.Unmarshal(data, tl)
+	if err != nil {
+		return err
+	}
+
+	t.tracks = tl.Tracks
+
+	return nil
+}
@@ -26,6 +26,14 @@ func (t *TrackList) Append(tr *track.Track) {
 	t.tracks = append(t.tracks, tr)
 }
 
+// Tracks returns the copy of the tracks in the order of appending.
+func (t *TrackList) Tracks() []*track.Track {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return append([]*track.Track(nil), t.tracks...)
+}
+
 func (t *TrackList) Length() int {
 	t.mutex.RLock()
 	defer t.mutex.RUnlock()
@@ -37,11 +45,30 @@ func (t *TrackList) length() int {
 	return len(t.tracks)
 }
 
+func (t *TrackList) Played() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return min(t.iterator, t.length())
+}
+
+// Peek returns the track Next would return without advancing.
+func (t *TrackList) Peek() *track.Track {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	if t.iterator >= t.length() {
+		return nil
+	}
+
+	return t.tracks[t.iterator]
+}
+
 func (t *TrackList) Next() *track.Track {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 
-	if t.iterator == t.length() {
+	if t.iterator >= t.length() {
 		return nil
 	}
 
@@ -51,6 +78,32 @@ func (t *TrackList) Next() *track.Track {
 	return tr
 }
 
+// NextCycled is Next starting from the beginning when the tracks are exhausted.
+func (t *TrackList) NextCycled() *track.Track {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.length() == 0 {
+		return nil
+	}
+
+	tr := t.tracks[t.iterator%t.length()]
+	t.iterator++
+
+	return tr
+}
+
+func (t *TrackList) Last() *track.Track {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	if t.length() == 0 {
+		return nil
+	}
+
+	return t.tracks[t.length()-1]
+}
+
 func (t *TrackList) ResetIterator() {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
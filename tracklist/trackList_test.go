@@ -1,6 +1,7 @@
 package tracklist_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -56,6 +57,38 @@ func TestTrackList(t *testing.T) {
 					assert.Nil(t, trNext)
 				})
 			})
+			t.Run("Played counts returned tracks", func(t *testing.T) {
+				tl := tracklist.New()
+				tl.Append(trRecorded)
+				tl.Append(trRecorded)
+				assert.Equal(t, 0, tl.Played())
+
+				tl.Next()
+				assert.Equal(t, 1, tl.Played())
+
+				tl.ResetIterator()
+				assert.Equal(t, 0, tl.Played())
+			})
+			t.Run("NextCycled starts from the beginning", func(t *testing.T) {
+				tl := tracklist.New()
+				assert.Nil(t, tl.NextCycled())
+
+				tl.Append(trRecorded)
+				tl.Append(trRecorded)
+
+				for i := 0; i < 3; i++ {
+					assert.NotNil(t, tl.NextCycled())
+				}
+				assert.Equal(t, 2, tl.Played())
+				assert.Nil(t, tl.Next())
+			})
+			t.Run("Last track", func(t *testing.T) {
+				tl := tracklist.New()
+				assert.Nil(t, tl.Last())
+
+				tl.Append(trRecorded)
+				assert.Equal(t, trRecorded, tl.Last())
+			})
 			t.Run("Next successfully", func(t *testing.T) {
 				t.Run("One track", func(t *testing.T) {
 					tl := tracklist.New()
@@ -67,9 +100,13 @@ func TestTrackList(t *testing.T) {
 				t.Run("Two tracks", func(t *testing.T) {
 					tl := tracklist.New()
 
+					greet := func(name string) func() string {
+						return func() string { return "hey " + name }
+					}
+
 					names := []string{"Alice", "Mary"}
 					for _, name := range names {
-						fn := func() string { return "hey " + name }
+						fn := greet(name)
 
 						resultStr := ""
 						tr := track.New().Call(fn).ResultsIn(&resultStr)
@@ -79,7 +116,7 @@ func TestTrackList(t *testing.T) {
 					}
 
 					for _, name := range names {
-						fn := func() string { return "hey girl" }
+						fn := greet("girl")
 						tr := track.New().Call(fn)
 
 						trNext := tl.Next()
@@ -95,3 +132,26 @@ func TestTrackList(t *testing.T) {
 		})
 	})
 }
+
+func TestTrackListJSON(t *testing.T) {
+	fn := func() string { return "hey" }
+	resultStr := ""
+	tr := track.New().Call(fn).ResultsIn(&resultStr)
+	tr.Record()
+
+	tl := tracklist.New()
+	tl.Append(tr)
+
+	dump, err := json.Marshal(tl)
+	assert.Nil(t, err)
+
+	tlRestored := tracklist.New()
+	err = json.Unmarshal(dump, tlRestored)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, tlRestored.Length())
+
+	resultStr = ""
+	err = tlRestored.Next().Playback(&resultStr)
+	assert.Nil(t, err)
+	assert.Equal(t, "hey", resultStr)
+}
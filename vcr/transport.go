@@ -0,0 +1,209 @@
+package vcr
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-vcr/cassete"
+	"go-vcr/track"
+)
+
+const transportTrackName = "net/http.RoundTrip"
+
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header `yaml:",omitempty"`
+	Body   string      `yaml:",omitempty"`
+}
+
+type Response struct {
+	Request Request
+
+	StatusCode int
+	Status     string
+	Proto      string
+	Header     http.Header `yaml:",omitempty"`
+	Body       string      `yaml:",omitempty"`
+}
+
+// Matcher copies the part of the request req which must match on replay into key.
+type Matcher func(key *Request, req *Request)
+
+func MatchMethod(key *Request, req *Request) {
+	key.Method = req.Method
+}
+
+func MatchURL(key *Request, req *Request) {
+	key.URL = req.URL
+}
+
+func MatchBody(key *Request, req *Request) {
+	key.Body = req.Body
+}
+
+func MatchHeaders(names ...string) Matcher {
+	return func(key *Request, req *Request) {
+		for _, name := range names {
+			values := req.Header.Values(name)
+			if len(values) == 0 {
+				continue
+			}
+			if key.Header == nil {
+				key.Header = make(http.Header)
+			}
+			key.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+}
+
+var DefaultMatchers = []Matcher{MatchMethod, MatchURL}
+
+// Transport is the http.RoundTripper which records the HTTP exchanges
+// made by the underlying transport to the cassete and replays them.
+type Transport struct {
+	Cassete *cassete.Cassete
+
+	// Transport makes the real requests, http.DefaultTransport is used if nil.
+	Transport http.RoundTripper
+
+	// Matchers select the parts of the request used to find the recorded response,
+	// DefaultMatchers are used if empty.
+	Matchers []Matcher
+}
+
+func NewTransport(cas *cassete.Cassete, matchers ...Matcher) *Transport {
+	return &Transport{
+		Cassete:  cas,
+		Matchers: matchers,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	roundTrip := func(Request) (Response, error) {
+		return t.roundTrip(req, request)
+	}
+
+	var response Response
+	var errRoundTrip error
+	tr := track.New().Call(roundTrip).Named(transportTrackName).With(t.key(request)).ResultsIn(&response, &errRoundTrip)
+
+	err = t.Cassete.Exec(tr)
+	if err != nil {
+		return nil, err
+	}
+	if errRoundTrip != nil {
+		return nil, errRoundTrip
+	}
+
+	return response.httpResponse(req), nil
+}
+
+func (t *Transport) key(request Request) Request {
+	matchers := t.Matchers
+	if len(matchers) == 0 {
+		matchers = DefaultMatchers
+	}
+
+	key := Request{}
+	for _, match := range matchers {
+		match(&key, &request)
+	}
+
+	return key
+}
+
+func (t *Transport) roundTrip(req *http.Request, request Request) (Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	req = req.Clone(req.Context())
+	if req.Body != nil {
+		req.Body = io.NopCloser(strings.NewReader(request.Body))
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	response := Response{
+		Request: request,
+
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Proto:      resp.Proto,
+		Header:     resp.Header,
+		Body:       string(body),
+	}
+
+	return response, nil
+}
+
+// newRequest reads and closes the body of req.
+func newRequest(req *http.Request) (Request, error) {
+	request := Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header,
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return request, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return request, err
+	}
+
+	request.Body = string(body)
+
+	return request, nil
+}
+
+func (r Response) httpResponse(req *http.Request) *http.Response {
+	proto := r.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	protoMajor, protoMinor, _ := http.ParseHTTPVersion(proto)
+
+	header := r.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	status := r.Status
+	if status == "" {
+		status = strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode)
+	}
+
+	return &http.Response{
+		Status:        status,
+		StatusCode:    r.StatusCode,
+		Proto:         proto,
+		ProtoMajor:    protoMajor,
+		ProtoMinor:    protoMinor,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
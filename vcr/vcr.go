@@ -7,6 +7,9 @@ import (
 
 type CasseteMap map[uint64]*cassete.Cassete
 
+// Default is the VCR shared by the package users.
+var Default = New()
+
 type VCR struct {
 	cassetes CasseteMap
 
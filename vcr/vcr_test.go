@@ -1,9 +1,17 @@
 package vcr_test
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 
 	"go-vcr/cassete"
 	"go-vcr/vcr"
@@ -40,3 +48,165 @@ func TestTrackList(t *testing.T) {
 		assert.Equal(t, 0, v.Length())
 	})
 }
+
+type client struct {
+	calledCount int
+}
+
+func (c *client) Fetch(id int, name string) (string, error) {
+	c.calledCount++
+	if id < 0 {
+		return "", errors.New("wrong id")
+	}
+
+	return fmt.Sprintf("%d:%s", id, name), nil
+}
+
+func TestWrap(t *testing.T) {
+	t.Run("Wrapped function records and replays calls", func(t *testing.T) {
+		c := &client{}
+		cas := cassete.New()
+
+		fetch := vcr.Wrap(cas, c.Fetch)
+		result, err := fetch(1, "Alice")
+		assert.Nil(t, err)
+		assert.Equal(t, "1:Alice", result)
+		assert.Equal(t, 1, c.calledCount)
+		assert.Equal(t, 1, cas.Length())
+
+		cas.SetMode(cassete.ModeReplay)
+
+		result, err = fetch(1, "Alice")
+		assert.Nil(t, err)
+		assert.Equal(t, "1:Alice", result)
+		assert.Equal(t, 1, c.calledCount)
+	})
+	t.Run("Errors of the function are returned", func(t *testing.T) {
+		c := &client{}
+		cas := cassete.New()
+
+		_, err := vcr.Wrap(cas, c.Fetch)(-1, "Alice")
+		assert.EqualError(t, err, "wrong id")
+	})
+	t.Run("Cassete error is returned in the error result", func(t *testing.T) {
+		c := &client{}
+		cas := cassete.New(cassete.WithMode(cassete.ModeReplay))
+
+		_, err := vcr.Wrap(cas, c.Fetch)(1, "Alice")
+		assert.True(t, errors.Is(err, cassete.ErrTrackNotFound))
+		assert.Equal(t, 0, c.calledCount)
+	})
+	t.Run("Variadic function", func(t *testing.T) {
+		calledCount := 0
+		sprintf := func(format string, args ...interface{}) string {
+			calledCount++
+			return fmt.Sprintf(format, args...)
+		}
+		cas := cassete.New()
+
+		fn := vcr.Wrap(cas, sprintf)
+		assert.Equal(t, "Alice is 30", fn("%s is %d", "Alice", 30))
+
+		cas.SetMode(cassete.ModeReplay)
+		assert.Equal(t, "Alice is 30", fn("%s is %d", "Alice", 30))
+		assert.Equal(t, 1, calledCount)
+	})
+	t.Run("Cassete error causes panic without error result", func(t *testing.T) {
+		cas := cassete.New(cassete.WithMode(cassete.ModeDisabled))
+
+		fn := vcr.Wrap(cas, func(int) int { return 0 })
+		assert.PanicsWithValue(t, cassete.ErrCasseteDisabled, func() { fn(1) })
+	})
+}
+
+func TestTransport(t *testing.T) {
+	calledCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calledCount++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Request-Id", r.Header.Get("X-Request-Id"))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, body)
+	}))
+	defer server.Close()
+
+	do := func(t *testing.T, client *http.Client, id string, body string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/users", strings.NewReader(body))
+		req.Header.Set("X-Request-Id", id)
+
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+		if err != nil {
+			return nil, ""
+		}
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp, string(respBody)
+	}
+
+	t.Run("Records and replays HTTP exchanges", func(t *testing.T) {
+		calledCount = 0
+		cas := cassete.New()
+		client := &http.Client{Transport: vcr.NewTransport(cas)}
+
+		_, body := do(t, client, "1", "Alice")
+		assert.Equal(t, "POST /users Alice", body)
+		assert.Equal(t, 1, calledCount)
+
+		dump, err := yaml.Marshal(cas)
+		assert.Nil(t, err)
+
+		casRestored := cassete.New()
+		err = yaml.Unmarshal(dump, casRestored)
+		assert.Nil(t, err)
+
+		client = &http.Client{Transport: vcr.NewTransport(casRestored)}
+
+		resp, body := do(t, client, "1", "Alice")
+		assert.Equal(t, "POST /users Alice", body)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "1", resp.Header.Get("X-Request-Id"))
+		assert.Equal(t, 1, calledCount)
+	})
+	t.Run("Binary bodies are kept in JSON cassete", func(t *testing.T) {
+		binary := string([]byte{0x1f, 0x8b, 0xff, 0xfe, 0x00, 0x80})
+		cas := cassete.New()
+		client := &http.Client{Transport: vcr.NewTransport(cas)}
+		_, body := do(t, client, "1", binary)
+
+		path := filepath.Join(t.TempDir(), "cassete.json")
+		err := cas.Save(path)
+		assert.Nil(t, err)
+
+		casLoaded, err := cassete.Load(path)
+		assert.Nil(t, err)
+
+		client = &http.Client{Transport: vcr.NewTransport(casLoaded)}
+		_, bodyReplayed := do(t, client, "1", binary)
+		assert.Equal(t, body, bodyReplayed)
+		assert.Equal(t, "POST /users "+binary, bodyReplayed)
+	})
+	t.Run("Requests are matched by matchers", func(t *testing.T) {
+		calledCount = 0
+		cas := cassete.New()
+		client := &http.Client{Transport: vcr.NewTransport(cas, vcr.MatchMethod, vcr.MatchURL, vcr.MatchBody)}
+
+		do(t, client, "1", "Alice")
+		do(t, client, "2", "Mary")
+		assert.Equal(t, 2, calledCount)
+
+		cas.SetMode(cassete.ModeReplay)
+		client = &http.Client{Transport: vcr.NewTransport(cas, vcr.MatchMethod, vcr.MatchURL, vcr.MatchBody)}
+
+		_, body := do(t, client, "3", "Mary")
+		assert.Equal(t, "POST /users Mary", body)
+		assert.Equal(t, 2, calledCount)
+
+		client = &http.Client{Transport: vcr.NewTransport(cas, vcr.MatchMethod, vcr.MatchURL, vcr.MatchHeaders("X-Request-Id"))}
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/users", strings.NewReader("Mary"))
+		req.Header.Set("X-Request-Id", "2")
+		_, err := client.Do(req)
+		assert.True(t, errors.Is(err, cassete.ErrTrackNotFound))
+	})
+}
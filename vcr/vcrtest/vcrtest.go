@@ -0,0 +1,91 @@
+// Package vcrtest binds the cassetes to the tests:
+// they are named after the tests, loaded and saved on the test cleanup.
+package vcrtest
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go-vcr/cassete"
+	"go-vcr/vcr"
+)
+
+const RecordEnv = "VCR_RECORD"
+
+var recordFlag = flag.Bool("vcr.record", false, "record cassetes instead of replaying them (also "+RecordEnv+" env)")
+
+// CasseteDir is the directory where ForTest keeps the cassetes.
+var CasseteDir = filepath.Join("testdata", "cassetes")
+
+// ForTest returns the cassete of the test t using the vcr.Default VCR.
+func ForTest(t testing.TB) *cassete.Cassete {
+	t.Helper()
+
+	return ForVCR(vcr.Default, t)
+}
+
+// ForVCR returns the cassete of the test t and adds it to the VCR v.
+// The cassete is loaded from the file named after the test and is replayed.
+// If the file is missing or recording is requested by the -vcr.record flag
+// or the VCR_RECORD env, the cassete records the calls and is saved on the test cleanup.
+func ForVCR(v *vcr.VCR, t testing.TB) *cassete.Cassete {
+	t.Helper()
+
+	path := CassetePath(t)
+
+	cas, err := cassete.Load(path)
+	switch {
+	case err == nil && !isRecordRequested():
+	case err == nil || errors.Is(err, cassete.ErrCasseteNotFound):
+		cas = cassete.New(cassete.WithMode(cassete.ModeRecord))
+	default:
+		t.Errorf("vcr: %s", err)
+		cas = cassete.New(cassete.WithMode(cassete.ModeDisabled))
+	}
+
+	v.Add(cas)
+	t.Cleanup(func() {
+		v.Delete(cas.ID())
+
+		switch cas.Mode() {
+		case cassete.ModeRecord, cassete.ModeReplayOrRecord, cassete.ModeRecordStale:
+		default:
+			return
+		}
+
+		err := cas.Save(path)
+		if err != nil {
+			t.Errorf("vcr: %s", err)
+		}
+	})
+
+	return cas
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9_.\-]+`)
+
+// CassetePath is the path of the cassete file of the test t.
+// Subtests are kept in the subdirectories of the parent test.
+func CassetePath(t testing.TB) string {
+	parts := strings.Split(t.Name(), "/")
+	for i := range parts {
+		parts[i] = unsafePathChars.ReplaceAllString(parts[i], "_")
+	}
+
+	return filepath.Join(CasseteDir, filepath.Join(parts...)+".yaml")
+}
+
+func isRecordRequested() bool {
+	if *recordFlag {
+		return true
+	}
+
+	record, _ := strconv.ParseBool(os.Getenv(RecordEnv))
+	return record
+}
@@ -0,0 +1,150 @@
+package vcrtest_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-vcr/cassete"
+	"go-vcr/track"
+	"go-vcr/vcr"
+	"go-vcr/vcr/vcrtest"
+)
+
+type client struct {
+	calledCount int
+}
+
+func (c *client) Fetch(id int, name string) (string, error) {
+	c.calledCount++
+	if id < 0 {
+		return "", errors.New("wrong id")
+	}
+
+	return fmt.Sprintf("%d:%s", id, name), nil
+}
+
+type fakeTB struct {
+	testing.TB
+
+	name     string
+	cleanups []func()
+	errors   []string
+}
+
+func (tb *fakeTB) Name() string {
+	return tb.name
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Cleanup(fn func()) {
+	tb.cleanups = append(tb.cleanups, fn)
+}
+
+func (tb *fakeTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *fakeTB) finish() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestForTest(t *testing.T) {
+	casseteDir := vcrtest.CasseteDir
+	vcrtest.CasseteDir = t.TempDir()
+	t.Cleanup(func() { vcrtest.CasseteDir = casseteDir })
+
+	t.Run("Cassete path is derived from the test name", func(t *testing.T) {
+		tb := &fakeTB{name: "TestClient/fetch user #1"}
+		assert.Equal(t, filepath.Join(vcrtest.CasseteDir, "TestClient", "fetch_user_1.yaml"), vcrtest.CassetePath(tb))
+	})
+	t.Run("Cassete is recorded when missing and replayed then", func(t *testing.T) {
+		c := &client{}
+		v := vcr.New()
+
+		{
+			tb := &fakeTB{name: "TestClient/record"}
+			cas := vcrtest.ForVCR(v, tb)
+			assert.Equal(t, cassete.ModeRecord, cas.Mode())
+			assert.Equal(t, 1, v.Length())
+
+			vcr.Wrap(cas, c.Fetch)(1, "Alice")
+			assert.True(t, cas.AssertAllPlayed(tb))
+
+			tb.finish()
+			assert.Empty(t, tb.errors)
+			assert.Equal(t, 0, v.Length())
+			assert.FileExists(t, vcrtest.CassetePath(tb))
+		}
+
+		{
+			tb := &fakeTB{name: "TestClient/record"}
+			cas := vcrtest.ForVCR(v, tb)
+			assert.Equal(t, cassete.ModeReplay, cas.Mode())
+
+			result, err := vcr.Wrap(cas, c.Fetch)(1, "Alice")
+			assert.Nil(t, err)
+			assert.Equal(t, "1:Alice", result)
+			assert.Equal(t, 1, c.calledCount)
+			assert.True(t, cas.AssertAllPlayed(tb))
+
+			tb.finish()
+			assert.Empty(t, tb.errors)
+		}
+	})
+	t.Run("Cassete recording stale tracks is saved", func(t *testing.T) {
+		fetchOld := func(id int) string { return "old" }
+		fetch := func(id int, full bool) string { return "new" }
+		exec := func(cas *cassete.Cassete, tr *track.Track) string {
+			resultStr := ""
+			err := cas.Exec(tr.Named("fetch").ResultsIn(&resultStr))
+			assert.Nil(t, err)
+			return resultStr
+		}
+
+		tb := &fakeTB{name: "TestClient/stale"}
+		exec(vcrtest.ForTest(tb), track.New().Call(fetchOld).With(1))
+		tb.finish()
+
+		tb = &fakeTB{name: "TestClient/stale"}
+		cas := vcrtest.ForTest(tb)
+		cas.SetMode(cassete.ModeRecordStale)
+		assert.Equal(t, "new", exec(cas, track.New().Call(fetch).With(1, true)))
+		tb.finish()
+		assert.Empty(t, tb.errors)
+
+		casLoaded, err := cassete.Load(vcrtest.CassetePath(tb))
+		assert.Nil(t, err)
+		assert.Equal(t, "new", exec(casLoaded, track.New().Call(fetch).With(1, true)))
+	})
+	t.Run("Recording is requested by env", func(t *testing.T) {
+		tb := &fakeTB{name: "TestClient/env"}
+		vcrtest.ForTest(tb)
+		tb.finish()
+
+		t.Setenv(vcrtest.RecordEnv, "true")
+
+		tb = &fakeTB{name: "TestClient/env"}
+		cas := vcrtest.ForTest(tb)
+		assert.Equal(t, cassete.ModeRecord, cas.Mode())
+		tb.finish()
+	})
+	t.Run("Corrupted cassete is reported", func(t *testing.T) {
+		tb := &fakeTB{name: "TestClient/corrupted"}
+		path := vcrtest.CassetePath(tb)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("version: [1"), 0644)
+
+		cas := vcrtest.ForTest(tb)
+		assert.Len(t, tb.errors, 1)
+		assert.Equal(t, cassete.ModeDisabled, cas.Mode())
+		tb.finish()
+	})
+}
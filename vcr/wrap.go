@@ -0,0 +1,69 @@
+package vcr
+
+import (
+	"reflect"
+
+	"go-vcr/cassete"
+	"go-vcr/track"
+)
+
+// Wrap returns the function of the same signature as fn which calls fn
+// through the cassete, so the calls are recorded or replayed in dependence
+// on the cassete mode.
+// If the last result of fn is error, the cassete error is returned in it,
+// otherwise the cassete error causes panic.
+func Wrap[F any](cas *cassete.Cassete, fn F) F {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		panic(track.ErrNotFunc)
+	}
+
+	t := v.Type()
+	wrapped := reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		args := make([]interface{}, 0, len(in))
+		for i := range in {
+			if t.IsVariadic() && i == len(in)-1 {
+				for j := 0; j < in[i].Len(); j++ {
+					args = append(args, in[i].Index(j).Interface())
+				}
+				break
+			}
+			args = append(args, in[i].Interface())
+		}
+
+		results := make([]interface{}, t.NumOut())
+		for i := range results {
+			results[i] = reflect.New(t.Out(i)).Interface()
+		}
+
+		err := cas.Exec(track.New().Call(fn).With(args...).ResultsIn(results...))
+		if err != nil {
+			return errorOut(t, err)
+		}
+
+		out := make([]reflect.Value, len(results))
+		for i := range results {
+			out[i] = reflect.ValueOf(results[i]).Elem()
+		}
+
+		return out
+	})
+
+	return wrapped.Interface().(F)
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func errorOut(t reflect.Type, err error) []reflect.Value {
+	if t.NumOut() == 0 || t.Out(t.NumOut()-1) != errorType {
+		panic(err)
+	}
+
+	out := make([]reflect.Value, t.NumOut())
+	for i := range out {
+		out[i] = reflect.Zero(t.Out(i))
+	}
+	out[len(out)-1] = reflect.ValueOf(&err).Elem()
+
+	return out
+}